@@ -0,0 +1,52 @@
+package govalin
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/pkkummermo/govalin/internal/validation"
+)
+
+// Bind request headers to given struct
+//
+// BindHeader takes a pointer to a struct and sets the fields tagged with a header tag,
+// e.g. `header:"X-Api-Version"`, to the value of the corresponding request header,
+// converted to the type of the field. Slice fields receive all values of the header.
+// Missing headers leave the fields untouched. Returns a validation error listing the
+// headers which could not be converted.
+func (call *Call) BindHeader(obj any) error {
+	objValue := reflect.ValueOf(obj)
+	if objValue.Kind() != reflect.Pointer || objValue.Elem().Kind() != reflect.Struct {
+		return newErrorFromType(serverError, fmt.Errorf("must provide a pointer to a struct to bind headers"))
+	}
+
+	structValue := objValue.Elem()
+	errorDetails := []validation.ErrorDetail{}
+
+	for i := 0; i < structValue.NumField(); i++ {
+		field := structValue.Type().Field(i)
+		headerName, ok := field.Tag.Lookup("header")
+		if !ok || !field.IsExported() {
+			continue
+		}
+
+		values := call.req.Header.Values(headerName)
+		if len(values) == 0 {
+			continue
+		}
+
+		if err := setValuesFromStrings(structValue.Field(i), values); err != nil {
+			errorDetails = append(errorDetails, validation.NewParameterErrorDetail(
+				headerName,
+				fmt.Sprintf("Incorrect type. '%s' is not of type '%s'", values[0], field.Type),
+			))
+		}
+	}
+
+	if len(errorDetails) > 0 {
+		return validation.NewError(validation.NewErrorResponse(http.StatusBadRequest, errorDetails...))
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package govalin
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Serve a cacheable JSON response
+//
+// ServeCacheable checks the If-None-Match and If-Modified-Since headers of the request
+// against given ETag and last modified time. If the client's cached version is fresh, a
+// 304 Not Modified is sent without calling produce. Otherwise produce is called and its
+// result is sent as JSON with the ETag and Last-Modified headers set. An empty ETag or a
+// zero last modified time disables the respective check.
+func (call *Call) ServeCacheable(lastModified time.Time, etag string, produce func() any) {
+	if etag != "" && !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+		etag = `"` + etag + `"`
+	}
+
+	if etag != "" {
+		call.w.Header().Set("ETag", etag)
+	}
+
+	if !lastModified.IsZero() {
+		call.w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if call.isCacheFresh(lastModified, etag) {
+		call.w.Header().Del("Content-Type")
+		call.Status(http.StatusNotModified)
+		call.sendStatusOrDefault()
+		return
+	}
+
+	call.JSON(produce())
+}
+
+// isCacheFresh checks whether the client's cached version is fresh according to
+// the conditional request headers. If-None-Match takes precedence over If-Modified-Since.
+func (call *Call) isCacheFresh(lastModified time.Time, etag string) bool {
+	if ifNoneMatch := call.req.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if etag == "" {
+			return false
+		}
+
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	ifModifiedSince := call.req.Header.Get("If-Modified-Since")
+	if ifModifiedSince == "" || lastModified.IsZero() {
+		return false
+	}
+
+	modifiedSince, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+
+	return !lastModified.Truncate(time.Second).After(modifiedSince)
+}
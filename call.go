@@ -1,15 +1,21 @@
 package govalin
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkkummermo/govalin/internal/validation"
+	"go.uber.org/zap"
 	"golang.org/x/exp/maps"
 )
 
@@ -18,24 +24,52 @@ type raw struct {
 	Req *http.Request
 }
 
+// CompleteFunc receives the final status, the number of bytes written and the
+// time elapsed when handling a request.
+type CompleteFunc func(status int, bytesWritten int64, elapsed time.Duration)
+
+type onceResult struct {
+	value any
+	err   error
+}
+
 type Call struct {
+	config        *Config
 	status        int
 	statusWritten bool
+	aborted       bool
 	w             http.ResponseWriter
 	req           *http.Request
 	pathParams    map[string]string
 	bodyBytes     []byte
+	unlimitedBody bool
 	charset       string
+	startTime     time.Time
+	onComplete    *[]CompleteFunc
+	errorDetails  []validation.ErrorDetail
+	logger        *zap.SugaredLogger
+	onceResults   map[string]onceResult
+	errorHandler  ErrorHandlerFunc
+	handlingError bool
+	variants      map[string]string
 	Raw           raw
 }
 
-func newCallFromRequest(w http.ResponseWriter, req *http.Request, pathParams map[string]string) Call {
+func newCallFromRequest(
+	w http.ResponseWriter,
+	req *http.Request,
+	pathParams map[string]string,
+	config *Config,
+) Call {
 	return Call{
+		config:     config,
 		w:          w,
 		req:        req,
 		status:     0,
 		pathParams: pathParams,
 		charset:    "utf-8",
+		startTime:  time.Now(),
+		onComplete: &[]CompleteFunc{},
 		Raw: raw{
 			W:   &w,
 			Req: req,
@@ -43,12 +77,212 @@ func newCallFromRequest(w http.ResponseWriter, req *http.Request, pathParams map
 	}
 }
 
+// Clone the call into a derived call
+//
+// Clone creates a new call for the same request, usable for sub-request simulation
+// such as batch processing or internal dispatching. The derived call shares the
+// following with the original call:
+//
+//   - The response writer and the underlying request (and thereby its context). Once
+//     the response status has been written by either call, neither call writes it again.
+//   - The callbacks registered using OnComplete, which are all called when the request
+//     has been handled.
+//   - The values computed using Once.
+//
+// Path params, the cached body, the feature variants, the request scoped logger, the
+// charset, the unlimited body setting and the error handler are copied, so they can be
+// changed on the derived call without affecting the original. The response status and
+// the validation errors added using AddValidationError are reset. Use CloneWith to derive
+// a call with another path and body.
+func (call *Call) Clone() *Call {
+	pathParams := make(map[string]string, len(call.pathParams))
+	for key, value := range call.pathParams {
+		pathParams[key] = value
+	}
+
+	clone := newCallFromRequest(call.w, call.req, pathParams, call.config)
+	clone.statusWritten = call.statusWritten
+	clone.unlimitedBody = call.unlimitedBody
+	clone.charset = call.charset
+	clone.startTime = call.startTime
+	clone.onComplete = call.onComplete
+	clone.logger = call.logger
+	clone.errorHandler = call.errorHandler
+
+	if call.onceResults == nil {
+		call.onceResults = map[string]onceResult{}
+	}
+	clone.onceResults = call.onceResults
+
+	if call.variants != nil {
+		clone.variants = make(map[string]string, len(call.variants))
+		for flag, variant := range call.variants {
+			clone.variants[flag] = variant
+		}
+	}
+
+	if call.bodyBytes != nil {
+		clone.bodyBytes = make([]byte, len(call.bodyBytes))
+		copy(clone.bodyBytes, call.bodyBytes)
+	}
+
+	return &clone
+}
+
+// Clone the call into a derived call for another path and body
+//
+// CloneWith works like Clone, but derives the call from a copy of the request with given
+// path, path params and body, e.g. for dispatching the items of a batch request as if
+// they were separate requests. The derived request keeps the method, headers, query and
+// context of the original request. A nil body gives the derived call an empty body.
+func (call *Call) CloneWith(path string, pathParams map[string]string, body []byte) *Call {
+	clone := call.Clone()
+
+	req := call.req.Clone(call.req.Context())
+	req.URL.Path = path
+	req.URL.RawPath = ""
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+
+	clone.req = req
+	clone.Raw.Req = req
+
+	clone.pathParams = make(map[string]string, len(pathParams))
+	for key, value := range pathParams {
+		clone.pathParams[key] = value
+	}
+
+	clone.bodyBytes = make([]byte, len(body))
+	copy(clone.bodyBytes, body)
+
+	return clone
+}
+
+// Get a channel closed when the request is done
+//
+// Done returns the Done channel of the request context, which is closed when the
+// client disconnects or the request is otherwise cancelled. Long running handlers
+// can use it to stop working when nobody is waiting for the result:
+//
+//	select {
+//	case result := <-runQuery(call):
+//		call.JSON(result)
+//	case <-call.Done():
+//		// The client went away, abort the query
+//		return
+//	}
+func (call *Call) Done() <-chan struct{} {
+	return call.req.Context().Done()
+}
+
+// Compute a value once per request
+//
+// Once calls compute the first time it's called with given key during a request, and
+// returns the cached value and error for subsequent calls with the same key. This lets
+// several before handlers and the endpoint handler share derived values, such as a parsed
+// auth token, without recomputing them. A request is handled by a single goroutine, so
+// Once isn't safe for concurrent use from goroutines started by the handler.
+func (call *Call) Once(key string, compute func() (any, error)) (any, error) {
+	if result, ok := call.onceResults[key]; ok {
+		return result.value, result.err
+	}
+
+	value, err := compute()
+
+	if call.onceResults == nil {
+		call.onceResults = map[string]onceResult{}
+	}
+	call.onceResults[key] = onceResult{value: value, err: err}
+
+	return value, err
+}
+
+// Get the variant of a feature flag
+//
+// Variant returns the variant of given feature flag assigned to the request by the
+// configured variant resolver. Returns an empty string if no variant is assigned.
+func (call *Call) Variant(flag string) string {
+	return call.variants[flag]
+}
+
+// Register a callback receiving the response summary
+//
+// OnComplete registers a callback which is called when the request has been
+// fully handled, receiving the final status, the number of bytes written to
+// the response and the time elapsed since the request was received. Multiple
+// callbacks are called in the order they were registered.
+func (call *Call) OnComplete(completeFunc CompleteFunc) {
+	*call.onComplete = append(*call.onComplete, completeFunc)
+}
+
+// complete calls the registered complete callbacks with the response summary.
+func (call *Call) complete(writer *responseWriter) {
+	elapsed := time.Since(call.startTime)
+
+	for _, completeFunc := range *call.onComplete {
+		completeFunc(writer.Status(), writer.bytesWritten, elapsed)
+	}
+}
+
+// Get the size of the request body
+//
+// RequestSize returns the size of the request body in bytes. The Content-Length of
+// the request is used when given. When absent, e.g. for chunked requests, the number
+// of bytes read by the body accessors is returned, or -1 if the body hasn't been read.
+func (call *Call) RequestSize() int64 {
+	if call.req.ContentLength >= 0 {
+		return call.req.ContentLength
+	}
+
+	if call.bodyBytes != nil {
+		return int64(len(call.bodyBytes))
+	}
+
+	return -1
+}
+
+// Get the number of bytes written to the response
+//
+// ResponseSize returns the number of body bytes written to the response so far,
+// excluding headers. Returns -1 if the call isn't writing through a govalin
+// response writer.
+func (call *Call) ResponseSize() int64 {
+	if writer, ok := call.w.(*responseWriter); ok {
+		return writer.bytesWritten
+	}
+
+	return -1
+}
+
+// Disable the body size limit for the request
+//
+// UnlimitedBody makes the body of the request be read in full, without the max body
+// read size limit. This exposes the server to denial of service through huge bodies,
+// so it should only be used for endpoints receiving requests from trusted callers,
+// e.g. by calling it from a before handler verifying the caller. Must be called before
+// the body is read.
+func (call *Call) UnlimitedBody() {
+	call.unlimitedBody = true
+}
+
 // readBody reads the body as bytes and caches the value on call.
 func (call *Call) readBody() ([]byte, error) {
 	if call.bodyBytes != nil {
 		return call.bodyBytes, nil
 	}
 
+	if call.unlimitedBody {
+		bytes, err := io.ReadAll(call.req.Body)
+		if err != nil {
+			call.bodyBytes = []byte{}
+			return []byte{}, fmt.Errorf("failed to read request body. %w", err)
+		}
+
+		call.bodyBytes = bytes
+
+		return call.bodyBytes, nil
+	}
+
 	limitedReader := io.LimitReader(call.req.Body, maxBodyReadSize)
 
 	bytes, err := io.ReadAll(limitedReader)
@@ -62,7 +296,7 @@ func (call *Call) readBody() ([]byte, error) {
 	if len(bytes) == int(maxBodyReadSize) {
 		if numBytes, readError := call.req.Body.Read(make([]byte, 1)); readError == nil && numBytes == 1 {
 			call.bodyBytes = []byte{}
-			return []byte{}, fmt.Errorf("request body was too big, could not read full body")
+			return []byte{}, newErrorFromType(userError, errBodyTooLarge)
 		}
 	}
 
@@ -71,7 +305,36 @@ func (call *Call) readBody() ([]byte, error) {
 	return call.bodyBytes, nil
 }
 
+// writeBody runs given body through the configured response filters and writes it to the response.
+func (call *Call) writeBody(body []byte) {
+	contentType := call.w.Header().Get("Content-Type")
+	for _, responseFilter := range call.config.responseFilters {
+		body = responseFilter(call, contentType, body)
+	}
+
+	call.sendStatusOrDefault()
+
+	_, err := call.w.Write(body)
+	if err != nil {
+		log.Errorf("Error when trying write to response, %v", err)
+	}
+}
+
+// setContentTypeIfAbsent sets the content type of the response unless one has already been set.
+func (call *Call) setContentTypeIfAbsent(contentType string) {
+	if call.w.Header().Get("Content-Type") != "" {
+		return
+	}
+
+	call.w.Header().Set("Content-Type", contentType)
+}
+
 func (call *Call) sendStatusOrDefault() {
+	// The status may have been written through a call sharing the response writer
+	if writer, ok := call.w.(*responseWriter); ok && writer.status != 0 {
+		call.statusWritten = true
+	}
+
 	if call.statusWritten {
 		return
 	}
@@ -118,11 +381,49 @@ func (call *Call) FormParamOrDefault(key string, def string) string {
 	return formParam
 }
 
+// Get form param for given key trimmed for whitespace
+//
+// Returns the form param value as string with leading and trailing
+// whitespace removed.
+func (call *Call) FormParamTrimmed(key string) string {
+	return strings.TrimSpace(call.FormParam(key))
+}
+
+// Get trimmed form param by key, if empty, use default
+//
+// Returns the form param value trimmed for whitespace, or the given default
+// value if the trimmed value is an empty string.
+func (call *Call) FormParamTrimmedOrDefault(key string, def string) string {
+	formParam := call.FormParamTrimmed(key)
+
+	if formParam == "" {
+		return def
+	}
+
+	return formParam
+}
+
 // Get query param for given key
 //
-// Returns the query param value as string.
+// Returns the query param value as string. If case-insensitive query params are
+// enabled, a param with a key differing only in case is used when there is no exact
+// match. If several keys differ only in case, the first one in sorted order is used.
 func (call *Call) QueryParam(key string) string {
-	return call.req.URL.Query().Get(key)
+	query := call.req.URL.Query()
+	if !call.config.caseInsensitiveQuery || query.Has(key) {
+		return query.Get(key)
+	}
+
+	queryKeys := maps.Keys(query)
+	sort.Strings(queryKeys)
+
+	for _, queryKey := range queryKeys {
+		if strings.EqualFold(queryKey, key) {
+			return query.Get(queryKey)
+		}
+	}
+
+	return ""
 }
 
 // Get query param by key, if empty, use default
@@ -139,6 +440,147 @@ func (call *Call) QueryParamOrDefault(key string, def string) string {
 	return queryParam
 }
 
+// Get query param for given key trimmed for whitespace
+//
+// Returns the query param value as string with leading and trailing
+// whitespace removed.
+func (call *Call) QueryParamTrimmed(key string) string {
+	return strings.TrimSpace(call.QueryParam(key))
+}
+
+// Get trimmed query param by key, if empty, use default
+//
+// Returns the query param value trimmed for whitespace, or the given default
+// value if the trimmed value is an empty string. This makes whitespace-only
+// values such as `?q=%20` fall back to the default.
+func (call *Call) QueryParamTrimmedOrDefault(key string, def string) string {
+	queryParam := call.QueryParamTrimmed(key)
+
+	if queryParam == "" {
+		return def
+	}
+
+	return queryParam
+}
+
+// Get comma separated query param values for given key
+//
+// Splits a comma separated query param value such as `?ids=1,2,3` into its values,
+// trimming whitespace and skipping empty values. Returns an empty slice if the
+// query param is absent.
+func (call *Call) QueryParamCSV(key string) []string {
+	values := []string{}
+
+	for _, value := range strings.Split(call.QueryParam(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// Get comma separated query param values for given key as ints
+//
+// Works like QueryParamCSV, but parses each value as an int. Returns a validation
+// error if any of the values is not a valid int.
+func (call *Call) QueryParamIntCSV(key string) ([]int, error) {
+	values := call.QueryParamCSV(key)
+	ints := make([]int, 0, len(values))
+
+	for _, value := range values {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return []int{}, validation.NewError(
+				validation.NewErrorResponse(
+					http.StatusBadRequest,
+					validation.NewParameterErrorDetail(key, fmt.Sprintf("Expected a list of integers, got '%s'", value)),
+				),
+			)
+		}
+
+		ints = append(ints, parsed)
+	}
+
+	return ints, nil
+}
+
+// Get query param as an int clamped to given range
+//
+// Parses the query param value as an int and clamps it to the range given by
+// minValue and maxValue. If the value is absent or not a valid int, the default is used.
+func (call *Call) QueryParamIntInRange(key string, minValue int, maxValue int, def int) int {
+	value, err := strconv.Atoi(call.QueryParam(key))
+	if err != nil {
+		return def
+	}
+
+	if value < minValue {
+		return minValue
+	}
+
+	if value > maxValue {
+		return maxValue
+	}
+
+	return value
+}
+
+// Get query param as an int within given range or fail
+//
+// Parses the query param value as an int. If the value is absent the default is
+// returned. If the value is not a valid int or outside the range given by minValue
+// and maxValue, a validation error describing the allowed range is returned.
+func (call *Call) QueryParamIntInRangeStrict(key string, minValue int, maxValue int, def int) (int, error) {
+	queryParam := call.QueryParam(key)
+	if queryParam == "" {
+		return def, nil
+	}
+
+	value, err := strconv.Atoi(queryParam)
+	if err != nil || value < minValue || value > maxValue {
+		return def, validation.NewError(
+			validation.NewErrorResponse(
+				http.StatusBadRequest,
+				validation.NewParameterErrorDetail(
+					key,
+					fmt.Sprintf("Expected an integer between %d and %d, got '%s'", minValue, maxValue, queryParam),
+				),
+			),
+		)
+	}
+
+	return value, nil
+}
+
+// Get query param restricted to a set of allowed values
+//
+// Returns the query param value if it's one of the allowed values. If the value is
+// absent the default is returned. If the value is present but not allowed, a validation
+// error listing the allowed values is returned.
+func (call *Call) QueryParamEnum(key string, allowed []string, def string) (string, error) {
+	queryParam := call.QueryParam(key)
+	if queryParam == "" {
+		return def, nil
+	}
+
+	for _, allowedValue := range allowed {
+		if queryParam == allowedValue {
+			return queryParam, nil
+		}
+	}
+
+	return def, validation.NewError(
+		validation.NewErrorResponse(
+			http.StatusBadRequest,
+			validation.NewParameterErrorDetail(
+				key,
+				fmt.Sprintf("Expected one of '%s', got '%s'", strings.Join(allowed, "', '"), queryParam),
+			),
+		),
+	)
+}
+
 // Get path param based on key.
 func (call *Call) PathParam(key string) string {
 	if _, ok := call.pathParams[key]; !ok {
@@ -211,60 +653,126 @@ func (call *Call) Status(statusCode int) {
 	call.status = statusCode
 }
 
-// Send text as pure text to response
+// Get a request scoped logger
 //
-// Text will set the content-type of the response as text/plain and write it to the response.
-// If no other status has been given the response, it will write a 200 OK to the response.
-func (call *Call) Text(text string) {
-	call.w.Header().Add("Content-Type", "text/plain; charset="+call.charset)
+// Logger returns a logger pre-populated with the method and path of the request,
+// and the request ID from the X-Request-Id header if present, so that handler logs
+// can be correlated. Fields can be added for the rest of the request using AddLogFields.
+func (call *Call) Logger() *zap.SugaredLogger {
+	if call.logger == nil {
+		call.logger = log.With("method", call.req.Method, "path", call.req.URL.Path)
+
+		if requestID := call.req.Header.Get("X-Request-Id"); requestID != "" {
+			call.logger = call.logger.With("requestId", requestID)
+		}
+	}
+
+	return call.logger
+}
+
+// Add fields to the request scoped logger
+//
+// AddLogFields adds the given key value pairs to the logger returned by Logger
+// for the rest of the request, e.g. a before handler adding the authenticated user.
+func (call *Call) AddLogFields(keysAndValues ...any) {
+	call.logger = call.Logger().With(keysAndValues...)
+}
+
+// Add a validation error to the request
+//
+// AddValidationError accumulates a validation error for given field on the call.
+// This allows several before handlers to validate the request, collecting all
+// errors instead of failing on the first one. If any validation errors have been
+// added when the endpoint handler is about to run, the handler is skipped and a
+// single bad request response containing all the errors is sent instead.
+func (call *Call) AddValidationError(field string, reason string) {
+	call.errorDetails = append(call.errorDetails, validation.NewParameterErrorDetail(field, reason))
+}
+
+// Abort the request with given status
+//
+// AbortWithStatus sends the given status as a bare response and stops further
+// processing of the request, skipping any remaining before handlers and the
+// endpoint handler. This is the canonical way of rejecting a request from a
+// before handler.
+func (call *Call) AbortWithStatus(statusCode int) {
+	call.status = statusCode
 	call.sendStatusOrDefault()
+	call.aborted = true
+}
 
-	_, err := call.w.Write([]byte(text))
-	if err != nil {
-		log.Errorf("Error when trying write to response, %v", err)
-	}
+// Send text as pure text to response
+//
+// Text will set the content-type of the response as text/plain and write it to the response,
+// unless a content-type has already been set on the response. If no other status has been given
+// the response, it will write a 200 OK to the response.
+func (call *Call) Text(text string) {
+	call.setContentTypeIfAbsent("text/plain; charset=" + call.charset)
+	call.writeBody([]byte(text))
 }
 
 // Send text as HTML to response
 //
-// HTML will set the content-type of the response as text/html and write it to the response.
-// If no other status has been given the response, it will write a 200 OK to the response.
+// HTML will set the content-type of the response as text/html and write it to the response,
+// unless a content-type has already been set on the response. If no other status has been given
+// the response, it will write a 200 OK to the response.
 func (call *Call) HTML(text string) {
-	call.w.Header().Add("Content-Type", "text/html; charset="+call.charset)
-	call.sendStatusOrDefault()
-
-	_, err := call.w.Write([]byte(text))
-	if err != nil {
-		log.Errorf("Error when trying write to response, %v", err)
-	}
+	call.setContentTypeIfAbsent("text/html; charset=" + call.charset)
+	call.writeBody([]byte(text))
 }
 
 // Send obj as JSON to response
 //
-// JSON will set the content-type of the response as application/json and serializes the given
-// object as JSON, and writes it to the response. If no other status has been given the response,
-// it will write a 200 OK to the response.
+// JSON will set the content-type of the response as application/json, unless a content-type has
+// already been set on the response, and serializes the given object as JSON, and writes it to the
+// response. If no other status has been given the response, it will write a 200 OK to the response.
+// If the object can't be serialized, e.g. because it contains a channel or a function, a 500 Internal
+// Server Error is sent instead. In development mode the response includes the reason of the failure.
 func (call *Call) JSON(obj interface{}) {
-	call.w.Header().Add("Content-Type", "application/json; charset=utf-8")
-	jsonBytes, err := json.Marshal(obj)
-
+	jsonBytes, err := call.marshalJSON(obj)
 	if err != nil {
-		log.Errorf("error when trying to JSON marshall object, %v", err)
+		log.Errorf("error when trying to JSON marshall object of type %T, %v", obj, err)
+
+		// The error response replaces any status given by the handler, as the intended response can't be sent
+		if call.config.developmentMode {
+			call.sendErrorDetail(http.StatusInternalServerError, "jsonResponse", err.Error())
+			return
+		}
+
+		call.Error(err)
+		return
 	}
 
-	call.sendStatusOrDefault()
+	call.setContentTypeIfAbsent("application/json; charset=utf-8")
+	call.writeBody(jsonBytes)
+}
+
+// marshalJSON serializes given object as JSON, escaping HTML characters unless disabled by the config.
+func (call *Call) marshalJSON(obj any) ([]byte, error) {
+	if call.config.jsonEscapeHTML {
+		return json.Marshal(obj)
+	}
 
-	_, err = call.w.Write(jsonBytes)
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
 
-	if err != nil {
-		log.Errorf("error when trying write to response, %v", err)
+	if err := encoder.Encode(obj); err != nil {
+		return nil, err
 	}
+
+	// Encode terminates the value with a newline, which Marshal doesn't
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
 }
 
 // Get body as given struct
 //
 // BodyAs takes a pointer as input and tries to deserialize the body into the object
 // expecting the body to be JSON. Returns an error on failed unmarshalling or non-pointer.
+// Bodies exceeding the configured max JSON depth or elements are rejected as bad requests.
+// Fields of embedded structs are promoted like encoding/json does. time.Time fields in
+// other formats than RFC 3339 can be given the layout to parse them with using a `time`
+// struct tag, e.g. `json:"dob" time:"2006-01-02"`.
 func (call *Call) BodyAs(obj any) error {
 	bodyBytes, err := call.readBody()
 
@@ -276,6 +784,16 @@ func (call *Call) BodyAs(obj any) error {
 		return newErrorFromType(serverError, fmt.Errorf("must provide a pointer to correctly unmarshal body"))
 	}
 
+	err = checkJSONLimits(bodyBytes, call.config.maxJSONDepth, call.config.maxJSONElements)
+	if err != nil {
+		return newErrorFromType(userError, err)
+	}
+
+	bodyBytes, err = rewriteTimeFields(bodyBytes, reflect.TypeOf(obj))
+	if err != nil {
+		return err
+	}
+
 	err = json.Unmarshal(bodyBytes, obj)
 	if err != nil {
 		return newErrorFromType(userError, err)
@@ -284,51 +802,210 @@ func (call *Call) BodyAs(obj any) error {
 	return nil
 }
 
+// Get body as given object based on the content type
+//
+// BodyInto takes a pointer as input and tries to deserialize the body into the object
+// based on the content type of the request. JSON bodies (or bodies without a content type)
+// are handled like BodyAs. CSV bodies (text/csv) can be deserialized into a *[][]string
+// or a pointer to a slice of structs. When deserializing into structs, the first row of
+// the CSV body must be a header row naming the columns, which are mapped to the struct
+// fields by their `csv` tag or, if missing, by their case-insensitive field name. Decoders
+// registered using Config.RegisterDecoder are used for their content types. Returns an
+// unsupported media type error for any other content type.
+func (call *Call) BodyInto(obj any) error {
+	contentType := call.Header("Content-Type")
+	if contentType == "" {
+		return call.BodyAs(obj)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return newErrorFromType(unsupportedMediaTypeError, fmt.Errorf("invalid content type '%s'", contentType))
+	}
+
+	if decoder, found := call.config.decoders[mediaType]; found {
+		bodyBytes, readErr := call.readBody()
+		if readErr != nil {
+			return readErr
+		}
+
+		if decodeErr := decoder(bodyBytes, obj); decodeErr != nil {
+			return newErrorFromType(userError, decodeErr)
+		}
+
+		return nil
+	}
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return call.BodyAs(obj)
+	case mediaType == "text/csv":
+		bodyBytes, readErr := call.readBody()
+		if readErr != nil {
+			return readErr
+		}
+
+		return decodeCSV(bodyBytes, obj)
+	default:
+		return newErrorFromType(
+			unsupportedMediaTypeError,
+			fmt.Errorf("unsupported content type '%s'", mediaType),
+		)
+	}
+}
+
+// Get the status an error would produce
+//
+// StatusForError returns the HTTP status Call.Error would respond with for given
+// error, without sending a response. Useful for logging or metrics middleware
+// recording the status of errors handled differently by the handlers.
+func (call *Call) StatusForError(err error) int {
+	return statusForError(err)
+}
+
+func statusForError(err error) int {
+	var govalinErr *govalinError
+	if errors.As(err, &govalinErr) {
+		switch govalinErr.errorType {
+		case userError:
+			return http.StatusBadRequest
+		case serverError:
+			return http.StatusInternalServerError
+		case unsupportedMediaTypeError:
+			return http.StatusUnsupportedMediaType
+		case notAcceptableError:
+			return http.StatusNotAcceptable
+		case notFoundError:
+			return http.StatusNotFound
+		case badGatewayError:
+			return http.StatusBadGateway
+		case serviceUnavailableError:
+			return http.StatusServiceUnavailable
+		}
+	}
+
+	var validationErr *validation.Error
+	if errors.As(err, &validationErr) {
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
+
 // Handle an error
 //
-// Write a response based on given error. If the error is recognized as a
-// govalin error the error is handled specific according to the error.
+// Write a response based on given error. If an error handler has been registered
+// for the request using App.ErrorHandler, the error is passed to it. Otherwise, if
+// the error is recognized as a govalin error the error is handled specific according
+// to the error. The status of the response is given by StatusForError.
 func (call *Call) Error(err error) {
+	// Errors raised by the error handler itself fall back to the default handling
+	if call.errorHandler != nil && !call.handlingError {
+		call.handlingError = true
+		call.errorHandler(call, err)
+		call.handlingError = false
+
+		return
+	}
+
+	// The error replaces the intended response, so it also replaces any status given by the handler
+	status := statusForError(err)
+	call.status = status
+
 	var govalinErr *govalinError
 	if errors.As(err, &govalinErr) {
-		if govalinErr.errorType == userError {
-			call.Status(http.StatusBadRequest)
-		} else if govalinErr.errorType == serverError {
-			call.Status(http.StatusInternalServerError)
+		switch govalinErr.errorType {
+		case unsupportedMediaTypeError:
+			call.sendErrorDetail(status, "Content-Type", govalinErr.originalError.Error())
+			return
+		case notFoundError:
+			call.sendErrorDetail(status, "path", govalinErr.originalError.Error())
+			return
+		case notAcceptableError:
+			call.sendErrorDetail(status, "Accept", govalinErr.originalError.Error())
+			return
+		case badGatewayError:
+			call.sendErrorDetail(status, "upstream", govalinErr.originalError.Error())
+			return
+		case serviceUnavailableError:
+			call.sendErrorResponse(validation.NewError(validation.NewErrorResponse(status)).ErrorResponse)
+			return
+		case userError, serverError:
 		}
 
 		var unmarshalErr *json.UnmarshalTypeError
 		if errors.As(govalinErr.originalError, &unmarshalErr) {
-			call.JSON(validation.GetUnmarshalError(unmarshalErr).ErrorResponse)
+			if !call.config.verboseDecodeErrors {
+				log.Infof("Failed to decode JSON body. %v", unmarshalErr)
+				call.sendErrorDetail(status, "jsonBody", "Invalid request body")
+				return
+			}
+
+			call.sendErrorResponse(validation.GetUnmarshalError(unmarshalErr).ErrorResponse)
 			return
 		}
 
 		var jsonSyntaxErr *json.SyntaxError
 		if errors.As(govalinErr.originalError, &jsonSyntaxErr) {
-			call.JSON(validation.NewError(
-				validation.NewErrorResponse(
-					http.StatusBadRequest,
-					validation.NewParameterErrorDetail("jsonBody", "Invalid JSON found in body"),
-				),
-			).ErrorResponse)
+			if !call.config.verboseDecodeErrors {
+				log.Infof("Failed to decode JSON body. %v", jsonSyntaxErr)
+				call.sendErrorDetail(status, "jsonBody", "Invalid request body")
+				return
+			}
+
+			call.sendErrorDetail(
+				status,
+				"jsonBody",
+				fmt.Sprintf("Invalid JSON found in body at offset %d", jsonSyntaxErr.Offset),
+			)
 			return
 		}
 
-		log.Warnf("Unknown govalin error %w. Original err: %w. Error not handled", govalinErr, govalinErr.originalError)
+		var jsonLimitErr *jsonLimitError
+		if errors.As(govalinErr.originalError, &jsonLimitErr) {
+			call.sendErrorDetail(status, "jsonBody", jsonLimitErr.Error())
+			return
+		}
+
+		if govalinErr.errorType == userError {
+			call.sendErrorDetail(status, "request", govalinErr.originalError.Error())
+			return
+		}
+
+		// Don't leak internals of server errors to the client
+		log.Errorf("Server error when handling request. %v", govalinErr.originalError)
+		call.sendErrorResponse(validation.NewError(validation.NewErrorResponse(status)).ErrorResponse)
 
 		return
 	}
 
 	var validationErr *validation.Error
 	if errors.As(err, &validationErr) {
-		call.Status(http.StatusBadRequest)
-		call.JSON(validationErr.ErrorResponse)
+		call.sendErrorResponse(validationErr.ErrorResponse)
 		return
 	}
 
-	call.JSON(validation.NewError(
+	call.sendErrorResponse(validation.NewError(
+		validation.NewErrorResponse(
+			status,
+		),
+	).ErrorResponse)
+}
+
+// sendErrorResponse sends given error response as JSON. The content type is always set
+// to JSON, as the handler may have set another content type for its intended response.
+func (call *Call) sendErrorResponse(errorResponse *validation.ErrorResponse) {
+	call.w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	call.JSON(errorResponse)
+}
+
+// sendErrorDetail sends an error response with given status and a single error detail.
+func (call *Call) sendErrorDetail(status int, field string, reason string) {
+	call.status = status
+	call.sendErrorResponse(validation.NewError(
 		validation.NewErrorResponse(
-			http.StatusInternalServerError,
+			status,
+			validation.NewParameterErrorDetail(field, reason),
 		),
 	).ErrorResponse)
 }
@@ -1,11 +1,31 @@
 package govalin_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	nethttp "net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/ddliu/go-httpclient"
 	"github.com/pkkummermo/govalin"
 	"github.com/pkkummermo/govalin/internal/govalintesting"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"gopkg.in/yaml.v3"
 )
 
 func TestQueryParam(t *testing.T) {
@@ -115,3 +135,1448 @@ func TestHeaders(t *testing.T) {
 		)
 	})
 }
+
+func TestClone(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Post("/clone/{org}", func(call *govalin.Call) {
+			body := map[string]string{}
+			_ = call.BodyAs(&body)
+
+			clone := call.Clone()
+			clone.PathParams()["org"] = "changed"
+
+			clonedBody := map[string]string{}
+			_ = clone.BodyAs(&clonedBody)
+
+			clone.Text(call.PathParam("org") + clone.PathParam("org") + clonedBody["name"])
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response, err := http.Raw().PostJson(http.Host+"/clone/govalin", map[string]string{"name": "gopher"})
+		assert.NoError(t, err)
+
+		body, _ := response.ToString()
+		assert.Equal(
+			t,
+			"govalinchangedgopher",
+			body,
+			"Should copy path params and cached body to the cloned call",
+		)
+	})
+
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Post("/batch/{org}", func(call *govalin.Call) {
+			clone := call.CloneWith("/users/gopher", map[string]string{"user": "gopher"}, []byte(`{"name":"clone"}`))
+
+			body := map[string]string{}
+			_ = call.BodyAs(&body)
+			clonedBody := map[string]string{}
+			_ = clone.BodyAs(&clonedBody)
+
+			clone.Text(strings.Join([]string{
+				call.PathParam("org"), body["name"], clone.Raw.Req.URL.Path, clone.PathParam("user"), clonedBody["name"],
+			}, " "))
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response, err := http.Raw().PostJson(http.Host+"/batch/govalin", map[string]string{"name": "original"})
+		assert.NoError(t, err)
+
+		body, _ := response.ToString()
+		assert.Equal(
+			t,
+			"govalin original /users/gopher gopher clone",
+			body,
+			"Should derive the cloned call from given path, path params and body",
+		)
+	})
+
+	var serverLog bytes.Buffer
+	log.SetOutput(&serverLog)
+	defer log.SetOutput(os.Stderr)
+
+	completed := make(chan int, 2)
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/clone", func(call *govalin.Call) {
+			call.OnComplete(func(status int, _ int64, _ time.Duration) { completed <- status })
+			computed, _ := call.Once("value", func() (any, error) { return "original", nil })
+			call.Text(computed.(string))
+
+			clone := call.Clone()
+			clone.OnComplete(func(status int, _ int64, _ time.Duration) { completed <- status })
+			cloneComputed, _ := clone.Once("value", func() (any, error) { return "clone", nil })
+			clone.Status(201)
+			clone.Text(cloneComputed.(string))
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response := http.GetResponse("/clone")
+		body, _ := response.ToString()
+		assert.Equal(t, 200, response.StatusCode, "Should keep the status written by the original call")
+		assert.Equal(t, "originaloriginal", body, "Should share values computed using Once")
+		assert.NotContains(t, serverLog.String(), "superfluous", "Should not write the status again from the clone")
+		assert.Equal(t, 200, <-completed, "Should call complete callbacks of the original call")
+		assert.Equal(t, 200, <-completed, "Should call complete callbacks of the cloned call")
+	})
+}
+
+func TestQueryParamIntInRange(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/clamp", func(call *govalin.Call) {
+			call.Text(strconv.Itoa(call.QueryParamIntInRange("size", 1, 100, 20)))
+		})
+		app.Get("/strict", func(call *govalin.Call) {
+			size, err := call.QueryParamIntInRangeStrict("size", 1, 100, 20)
+			if err != nil {
+				call.Error(err)
+				return
+			}
+			call.Text(strconv.Itoa(size))
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(t, "50", http.Get("/clamp?size=50"), "Should return value within range")
+		assert.Equal(t, "100", http.Get("/clamp?size=500"), "Should clamp value above max")
+		assert.Equal(t, "1", http.Get("/clamp?size=0"), "Should clamp value below min")
+		assert.Equal(t, "20", http.Get("/clamp?size=foo"), "Should use default on invalid value")
+		assert.Equal(t, "20", http.Get("/clamp"), "Should use default on absent value")
+
+		assert.Equal(t, "50", http.Get("/strict?size=50"), "Should return value within range")
+		assert.Equal(t, "20", http.Get("/strict"), "Should use default on absent value")
+		assert.Equal(
+			t,
+			400,
+			http.GetResponse("/strict?size=500").StatusCode,
+			"Should fail with bad request on value out of range",
+		)
+	})
+}
+
+func TestOnComplete(t *testing.T) {
+	completed := make(chan string, 2)
+
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Before("/complete", func(call *govalin.Call) bool {
+			call.OnComplete(func(status int, bytesWritten int64, elapsed time.Duration) {
+				completed <- fmt.Sprintf("first %d %d", status, bytesWritten)
+			})
+			call.OnComplete(func(status int, bytesWritten int64, elapsed time.Duration) {
+				completed <- fmt.Sprintf("second %d %d", status, bytesWritten)
+			})
+			return true
+		})
+		app.Get("/complete", func(call *govalin.Call) {
+			call.Status(201)
+			call.Text("govalin")
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(t, "govalin", http.Get("/complete"))
+		assert.Equal(t, "first 201 7", <-completed, "Should call first callback with response summary")
+		assert.Equal(t, "second 201 7", <-completed, "Should call callbacks in registration order")
+	})
+}
+
+func TestTextContentType(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/text", func(call *govalin.Call) {
+			call.Text("govalin")
+		})
+		app.Get("/csv", func(call *govalin.Call) {
+			call.Header("Content-Type", "text/csv")
+			call.Text("go,valin")
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(
+			t,
+			[]string{"text/plain; charset=utf-8"},
+			http.GetResponse("/text").Header.Values("Content-Type"),
+			"Should default to text/plain content type",
+		)
+		assert.Equal(
+			t,
+			[]string{"text/csv"},
+			http.GetResponse("/csv").Header.Values("Content-Type"),
+			"Should keep already set content type without duplicating it",
+		)
+	})
+}
+
+func TestHTMLAndJSONContentType(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/html", func(call *govalin.Call) {
+			call.HTML("<h1>govalin</h1>")
+		})
+		app.Get("/xhtml", func(call *govalin.Call) {
+			call.Header("Content-Type", "application/xhtml+xml")
+			call.HTML("<h1>govalin</h1>")
+		})
+		app.Get("/json", func(call *govalin.Call) {
+			call.JSON("govalin")
+		})
+		app.Get("/problem", func(call *govalin.Call) {
+			call.Header("Content-Type", "application/problem+json")
+			call.JSON(map[string]string{"title": "govalin"})
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(
+			t,
+			[]string{"text/html; charset=utf-8"},
+			http.GetResponse("/html").Header.Values("Content-Type"),
+			"Should default to text/html content type",
+		)
+		assert.Equal(
+			t,
+			[]string{"application/xhtml+xml"},
+			http.GetResponse("/xhtml").Header.Values("Content-Type"),
+			"Should keep already set content type without duplicating it",
+		)
+		assert.Equal(
+			t,
+			[]string{"application/json; charset=utf-8"},
+			http.GetResponse("/json").Header.Values("Content-Type"),
+			"Should default to application/json content type",
+		)
+		assert.Equal(
+			t,
+			[]string{"application/problem+json"},
+			http.GetResponse("/problem").Header.Values("Content-Type"),
+			"Should keep already set content type without duplicating it",
+		)
+	})
+}
+
+func TestErrorContentType(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/report", func(call *govalin.Call) {
+			call.Header("Content-Type", "text/csv")
+			call.Error(fmt.Errorf("report failed"))
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response := http.GetResponse("/report")
+		assert.Equal(t, 500, response.StatusCode)
+		assert.Equal(
+			t,
+			[]string{"application/json; charset=utf-8"},
+			response.Header.Values("Content-Type"),
+			"Should send error responses as JSON regardless of the content type set by the handler",
+		)
+	})
+}
+
+func TestBodyInto(t *testing.T) {
+	type user struct {
+		Name string `csv:"name"`
+		Age  int
+	}
+
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Post("/users", func(call *govalin.Call) {
+			users := []user{}
+			if err := call.BodyInto(&users); err != nil {
+				call.Error(err)
+				return
+			}
+			call.JSON(users)
+		})
+		app.Post("/rows", func(call *govalin.Call) {
+			rows := [][]string{}
+			if err := call.BodyInto(&rows); err != nil {
+				call.Error(err)
+				return
+			}
+			call.Text(strconv.Itoa(len(rows)))
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response, _ := http.Raw().
+			WithHeader("Content-Type", "text/csv").
+			Do("POST", http.Host+"/users", nil, strings.NewReader("name,age\ngopher,13\ngovalin,1\n"))
+		body, _ := response.ToString()
+		assert.Equal(
+			t,
+			`[{"Name":"gopher","Age":13},{"Name":"govalin","Age":1}]`,
+			body,
+			"Should map CSV rows to structs using the header row",
+		)
+
+		response, _ = http.Raw().
+			WithHeader("Content-Type", "application/json").
+			Do("POST", http.Host+"/users", nil, strings.NewReader(`[{"Name":"gopher","Age":13}]`))
+		body, _ = response.ToString()
+		assert.Equal(t, `[{"Name":"gopher","Age":13}]`, body, "Should decode JSON bodies")
+
+		response, _ = http.Raw().
+			WithHeader("Content-Type", "text/csv").
+			Do("POST", http.Host+"/rows", nil, strings.NewReader("name,age\ngopher,13\n"))
+		body, _ = response.ToString()
+		assert.Equal(t, "2", body, "Should decode CSV into raw rows")
+
+		response, _ = http.Raw().
+			WithHeader("Content-Type", "text/csv").
+			Do("POST", http.Host+"/users", nil, strings.NewReader("name,age\ngopher,old\n"))
+		body, _ = response.ToString()
+		assert.Equal(t, 400, response.StatusCode, "Should fail on invalid CSV values")
+		assert.Contains(t, body, "csv[2].age", "Should report the failing row and column")
+
+		response, _ = http.Raw().
+			WithHeader("Content-Type", "application/xml").
+			Do("POST", http.Host+"/users", nil, strings.NewReader("<users/>"))
+		assert.Equal(t, 415, response.StatusCode, "Should fail on unsupported content types")
+	})
+}
+
+func TestTrimmedParams(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/query", func(call *govalin.Call) {
+			call.Text(call.QueryParamTrimmedOrDefault("q", "default"))
+		})
+		app.Post("/form", func(call *govalin.Call) {
+			call.Text(call.FormParamTrimmedOrDefault("q", "default"))
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(t, "default", http.Get("/query?q=%20"), "Should use default for whitespace-only query param")
+		assert.Equal(t, "govalin", http.Get("/query?q=%20govalin%20"), "Should trim query param")
+		assert.Equal(
+			t,
+			"default",
+			http.Post("/form", map[string]string{"q": "  "}),
+			"Should use default for whitespace-only form param",
+		)
+		assert.Equal(t, "govalin", http.Post("/form", map[string]string{"q": " govalin "}), "Should trim form param")
+	})
+}
+
+func TestJSONArray(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/array", func(call *govalin.Call) {
+			array := call.JSONArray()
+			defer array.Close()
+
+			for i := 0; i < 3; i++ {
+				_ = array.Append(map[string]int{"id": i})
+			}
+		})
+		app.Get("/empty", func(call *govalin.Call) {
+			array := call.JSONArray()
+			defer array.Close()
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response := http.GetResponse("/array")
+		body, _ := response.ToString()
+
+		assert.Equal(t, `[{"id":0},{"id":1},{"id":2}]`, body, "Should stream elements as a JSON array")
+		assert.Equal(t, "application/json; charset=utf-8", response.Header.Get("Content-Type"))
+		assert.Equal(t, "[]", http.Get("/empty"), "Should write an empty JSON array")
+	})
+}
+
+func TestForwarded(t *testing.T) {
+	govalintesting.HTTPTestUtilWithConfig(func(config *govalin.Config) {
+		config.TrustedProxies("127.0.0.1", "::1")
+	}, func(app *govalin.App) *govalin.App {
+		app.Get("/client", func(call *govalin.Call) {
+			call.Text(call.Scheme() + " " + call.IP())
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response, _ := http.Raw().
+			WithHeader("Forwarded", "for=192.0.2.60;proto=https;by=203.0.113.43").
+			WithHeader("X-Forwarded-For", "198.51.100.17").
+			Get(http.Host + "/client")
+		body, _ := response.ToString()
+		assert.Equal(t, "https 192.0.2.60", body, "Should prefer the Forwarded header")
+
+		response, _ = http.Raw().
+			WithHeader("Forwarded", `for="[2001:db8:cafe::17]:4711"`).
+			Get(http.Host + "/client")
+		body, _ = response.ToString()
+		assert.Equal(t, "http 2001:db8:cafe::17", body, "Should parse quoted IPv6 for entries")
+
+		response, _ = http.Raw().
+			WithHeader("X-Forwarded-For", "203.0.113.195, 70.41.3.18").
+			WithHeader("X-Forwarded-Proto", "https").
+			Get(http.Host + "/client")
+		body, _ = response.ToString()
+		assert.Equal(t, "https 70.41.3.18", body, "Should fall back to X-Forwarded-* headers")
+
+		response, _ = http.Raw().
+			WithHeader("Forwarded", "for=192.0.2.60;proto=https, for=198.51.100.17;proto=http").
+			Get(http.Host + "/client")
+		body, _ = response.ToString()
+		assert.Equal(t, "http 198.51.100.17", body, "Should ignore the proto of elements sent by the client")
+
+		response, _ = http.Raw().
+			WithHeader("X-Forwarded-Proto", "https, http").
+			Get(http.Host + "/client")
+		body, _ = response.ToString()
+		assert.Equal(t, "http 127.0.0.1", body, "Should use the proto added by the closest proxy")
+
+		response, _ = http.Raw().
+			WithHeader("Forwarded", "proto=javascript").
+			Get(http.Host + "/client")
+		body, _ = response.ToString()
+		assert.Equal(t, "http 127.0.0.1", body, "Should ignore schemes other than http and https")
+	})
+
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/client", func(call *govalin.Call) {
+			call.Text(call.Scheme() + " " + call.IP())
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response, _ := http.Raw().
+			WithHeader("Forwarded", "for=192.0.2.60;proto=https").
+			Get(http.Host + "/client")
+		body, _ := response.ToString()
+		assert.NotContains(t, body, "192.0.2.60", "Should ignore forwarding headers from untrusted proxies")
+		assert.True(t, strings.HasPrefix(body, "http "), "Should ignore forwarded proto from untrusted proxies")
+	})
+}
+
+func TestFullURL(t *testing.T) {
+	serverF := func(app *govalin.App) *govalin.App {
+		app.Get("/users", func(call *govalin.Call) {
+			call.Text(call.BaseURL() + " " + call.FullURL())
+		})
+
+		return app
+	}
+
+	govalintesting.HTTPTestUtilWithConfig(func(config *govalin.Config) {
+		config.TrustedProxies("127.0.0.1", "::1")
+	}, serverF, func(http govalintesting.GovalinHTTP) {
+		response, _ := http.Raw().
+			WithHeader("Forwarded", "proto=https;host=govalin.dev").
+			Get(http.Host + "/users?page=2")
+		body, _ := response.ToString()
+		assert.Equal(
+			t,
+			"https://govalin.dev https://govalin.dev/users?page=2",
+			body,
+			"Should use the Forwarded header",
+		)
+
+		response, _ = http.Raw().
+			WithHeader("X-Forwarded-Proto", "https").
+			WithHeader("X-Forwarded-Host", "api.govalin.dev").
+			Get(http.Host + "/users")
+		body, _ = response.ToString()
+		assert.Equal(
+			t,
+			"https://api.govalin.dev https://api.govalin.dev/users",
+			body,
+			"Should fall back to X-Forwarded-* headers",
+		)
+
+		response, _ = http.Raw().
+			WithHeader("Forwarded", "for=192.0.2.60;host=evil.com, for=198.51.100.17;proto=https;host=govalin.dev").
+			Get(http.Host + "/users")
+		body, _ = response.ToString()
+		assert.Equal(
+			t,
+			"https://govalin.dev https://govalin.dev/users",
+			body,
+			"Should ignore the host of Forwarded elements sent by the client",
+		)
+
+		response, _ = http.Raw().
+			WithHeader("X-Forwarded-Host", "evil.com, api.govalin.dev").
+			Get(http.Host + "/users")
+		body, _ = response.ToString()
+		assert.Equal(
+			t,
+			"http://api.govalin.dev http://api.govalin.dev/users",
+			body,
+			"Should use the X-Forwarded-Host added by the closest proxy",
+		)
+	})
+
+	govalintesting.HTTPTestUtil(serverF, func(http govalintesting.GovalinHTTP) {
+		response, _ := http.Raw().
+			WithHeader("X-Forwarded-Proto", "https").
+			WithHeader("X-Forwarded-Host", "evil.dev").
+			Get(http.Host + "/users?page=2")
+		body, _ := response.ToString()
+		assert.Equal(
+			t,
+			http.Host+" "+http.Host+"/users?page=2",
+			body,
+			"Should ignore forwarding headers from untrusted proxies",
+		)
+	})
+}
+
+func TestJSONLimits(t *testing.T) {
+	govalintesting.HTTPTestUtilWithConfig(func(config *govalin.Config) {
+		config.MaxJSONDepth(3).MaxJSONElements(3)
+	}, func(app *govalin.App) *govalin.App {
+		app.Post("/json", func(call *govalin.Call) {
+			var body any
+			if err := call.BodyAs(&body); err != nil {
+				call.Error(err)
+				return
+			}
+			call.Text("ok")
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		post := func(body string) *httpclient.Response {
+			response, _ := http.Raw().Do("POST", http.Host+"/json", nil, strings.NewReader(body))
+			return response
+		}
+
+		assert.Equal(t, 200, post(`{"a":[{"b":1}],"c":[1,2,3]}`).StatusCode, "Should accept body within limits")
+		assert.Equal(t, 400, post(`{"a":[{"b":[1]}]}`).StatusCode, "Should reject too deeply nested body")
+		assert.Equal(t, 400, post(`[1,2,3,4]`).StatusCode, "Should reject too large arrays")
+		assert.Equal(t, 400, post(`{"a":1,"b":2,"c":3,"d":4}`).StatusCode, "Should reject objects with too many keys")
+	})
+}
+
+func TestWriteFileFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":      &fstest.MapFile{Data: []byte("<h1>govalin</h1>")},
+		"assets/logo.svg": &fstest.MapFile{Data: []byte("<svg/>")},
+	}
+
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/files/{name}", func(call *govalin.Call) {
+			if err := call.WriteFileFS(fsys, call.PathParam("name")); err != nil {
+				call.Error(err)
+			}
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response := http.GetResponse("/files/index.html")
+		body, _ := response.ToString()
+		etag := response.Header.Get("ETag")
+
+		assert.Equal(t, "<h1>govalin</h1>", body, "Should serve file content")
+		assert.Equal(t, "text/html; charset=utf-8", response.Header.Get("Content-Type"), "Should detect content type")
+		assert.NotEmpty(t, etag, "Should synthesize an ETag")
+
+		response, _ = http.Raw().WithHeader("If-None-Match", etag).Get(http.Host + "/files/index.html")
+		assert.Equal(t, 304, response.StatusCode, "Should respond not modified on matching ETag")
+		assert.Equal(t, "bytes", response.Header.Get("Accept-Ranges"), "Should advertise range support")
+
+		assert.Equal(t, 404, http.GetResponse("/files/missing.html").StatusCode, "Should respond 404 on missing file")
+		assert.Equal(t, 500, http.GetResponse("/files/assets").StatusCode, "Should respond 500 on unreadable file")
+	})
+}
+
+func TestServeCacheable(t *testing.T) {
+	lastModified := time.Date(2022, time.October, 1, 12, 0, 0, 0, time.UTC)
+	produced := 0
+
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/cacheable", func(call *govalin.Call) {
+			call.ServeCacheable(lastModified, "v1", func() any {
+				produced++
+				return "govalin"
+			})
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response := http.GetResponse("/cacheable")
+		body, _ := response.ToString()
+		assert.Equal(t, `"govalin"`, body, "Should send produced result")
+		assert.Equal(t, `"v1"`, response.Header.Get("ETag"), "Should set quoted ETag")
+		assert.Equal(t, "Sat, 01 Oct 2022 12:00:00 GMT", response.Header.Get("Last-Modified"))
+
+		response, _ = http.Raw().WithHeader("If-None-Match", `"v1"`).Get(http.Host + "/cacheable")
+		assert.Equal(t, 304, response.StatusCode, "Should respond not modified on matching ETag")
+
+		response, _ = http.Raw().
+			WithHeader("If-Modified-Since", "Sat, 01 Oct 2022 12:00:00 GMT").
+			Get(http.Host + "/cacheable")
+		assert.Equal(t, 304, response.StatusCode, "Should respond not modified when not modified since")
+
+		response, _ = http.Raw().WithHeader("If-None-Match", `"v0"`).Get(http.Host + "/cacheable")
+		assert.Equal(t, 200, response.StatusCode, "Should respond with content on stale ETag")
+
+		assert.Equal(t, 2, produced, "Should only produce content when cache is stale")
+	})
+}
+
+func TestBindHeader(t *testing.T) {
+	type headers struct {
+		APIVersion int      `header:"X-Api-Version"`
+		Cursor     string   `header:"X-Cursor"`
+		Tags       []string `header:"X-Tag"`
+		Missing    string   `header:"X-Missing"`
+	}
+
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/headers", func(call *govalin.Call) {
+			boundHeaders := headers{}
+			if err := call.BindHeader(&boundHeaders); err != nil {
+				call.Error(err)
+				return
+			}
+			call.JSON(boundHeaders)
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response, _ := http.Raw().
+			WithHeader("X-Api-Version", "2").
+			WithHeader("X-Cursor", "abc").
+			WithHeader("X-Tag", "go").
+			Get(http.Host + "/headers")
+		body, _ := response.ToString()
+		assert.Equal(
+			t,
+			`{"APIVersion":2,"Cursor":"abc","Tags":["go"],"Missing":""}`,
+			body,
+			"Should bind headers to struct fields",
+		)
+
+		response, _ = http.Raw().WithHeader("X-Api-Version", "two").Get(http.Host + "/headers")
+		body, _ = response.ToString()
+		assert.Equal(t, 400, response.StatusCode, "Should fail on invalid header value")
+		assert.Contains(t, body, "X-Api-Version", "Should list the failing header")
+	})
+}
+
+func TestUnlimitedBody(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Before("/unlimited", func(call *govalin.Call) bool {
+			call.UnlimitedBody()
+			return true
+		})
+		app.Post("/unlimited", func(call *govalin.Call) {
+			body := map[string]string{}
+			if err := call.BodyAs(&body); err != nil {
+				call.Error(err)
+				return
+			}
+			call.Text(strconv.Itoa(len(body["data"])))
+		})
+		app.Post("/limited", func(call *govalin.Call) {
+			body := map[string]string{}
+			if err := call.BodyAs(&body); err != nil {
+				call.Status(413)
+				call.Text(err.Error())
+				return
+			}
+			call.Text(strconv.Itoa(len(body["data"])))
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		data := map[string]string{"data": strings.Repeat("a", 10000)}
+
+		response, _ := http.Raw().PostJson(http.Host+"/unlimited", data)
+		body, _ := response.ToString()
+		assert.Equal(t, "10000", body, "Should read body beyond the size limit")
+
+		response, _ = http.Raw().PostJson(http.Host+"/limited", data)
+		assert.Equal(t, 413, response.StatusCode, "Should keep the size limit on other routes")
+	})
+}
+
+func TestMultiStatus(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Post("/batch", func(call *govalin.Call) {
+			call.MultiStatus().
+				Add("1", 201, map[string]string{"name": "govalin"}).
+				Add("2", 409, nil).
+				Send()
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response := http.PostResponse("/batch", map[string]string{})
+		body, _ := response.ToString()
+
+		assert.Equal(t, 207, response.StatusCode, "Should respond with multi-status")
+		assert.Equal(
+			t,
+			`[{"id":"1","status":201,"body":{"name":"govalin"}},{"id":"2","status":409}]`,
+			body,
+			"Should serialize per-item results",
+		)
+	})
+}
+
+func TestQueryParamCSV(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/csv", func(call *govalin.Call) {
+			call.JSON(call.QueryParamCSV("ids"))
+		})
+		app.Get("/ints", func(call *govalin.Call) {
+			ids, err := call.QueryParamIntCSV("ids")
+			if err != nil {
+				call.Error(err)
+				return
+			}
+			call.JSON(ids)
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(t, `["a","b","c"]`, http.Get("/csv?ids=a,%20b,,c%20"), "Should split, trim and skip empty values")
+		assert.Equal(t, `[]`, http.Get("/csv"), "Should return empty list on absent param")
+		assert.Equal(t, `[1,2,3]`, http.Get("/ints?ids=1,2,3"), "Should parse int values")
+		assert.Equal(t, 400, http.GetResponse("/ints?ids=1,two").StatusCode, "Should fail on invalid int values")
+	})
+}
+
+func TestSSE(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/events", func(call *govalin.Call) {
+			stream := call.SSE()
+			_ = stream.SetRetry(3 * time.Second)
+
+			lastEventID, _ := strconv.Atoi(call.LastEventID())
+			for id := lastEventID + 1; id <= 2; id++ {
+				_ = stream.SendEventWithID(strconv.Itoa(id), "update", "go\nvalin")
+			}
+		})
+		app.Get("/line-breaks", func(call *govalin.Call) {
+			stream := call.SSE()
+
+			if err := stream.SendEventWithID("1\nevent: spoofed", "update", "govalin"); err == nil {
+				t.Error("Should reject IDs containing line breaks")
+			}
+			if err := stream.SendEvent("update\r", "govalin"); err == nil {
+				t.Error("Should reject event names containing line breaks")
+			}
+			_ = stream.SendEvent("", "go\r\nva\rlin")
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response := http.GetResponse("/events")
+		body, _ := response.ToString()
+
+		assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+		assert.Equal(
+			t,
+			"retry: 3000\n\n"+
+				"id: 1\nevent: update\ndata: go\ndata: valin\n\n"+
+				"id: 2\nevent: update\ndata: go\ndata: valin\n\n",
+			body,
+			"Should send events with id and retry fields",
+		)
+
+		response, _ = http.Raw().WithHeader("Last-Event-ID", "1").Get(http.Host + "/events")
+		body, _ = response.ToString()
+		assert.Equal(
+			t,
+			"retry: 3000\n\nid: 2\nevent: update\ndata: go\ndata: valin\n\n",
+			body,
+			"Should resume from last event ID",
+		)
+
+		assert.Equal(
+			t,
+			"data: go\ndata: va\ndata: lin\n\n",
+			http.Get("/line-breaks"),
+			"Should split data on all line breaks, and skip events with invalid fields",
+		)
+	})
+}
+
+func TestSSEConcurrentSends(t *testing.T) {
+	sendAfterClose := make(chan error, 1)
+
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/events", func(call *govalin.Call) {
+			stream := call.SSE()
+
+			var wg sync.WaitGroup
+			for sender := 0; sender < 10; sender++ {
+				wg.Add(1)
+				go func(sender int) {
+					defer wg.Done()
+					for event := 0; event < 10; event++ {
+						_ = stream.SendEvent("update", fmt.Sprintf("%d\n%d", sender, event))
+					}
+				}(sender)
+			}
+			wg.Wait()
+
+			stream.Close()
+			sendAfterClose <- stream.SendEvent("update", "closed")
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		body, _ := http.GetResponse("/events").ToString()
+		events := strings.Split(strings.TrimSuffix(body, "\n\n"), "\n\n")
+
+		assert.Len(t, events, 100, "Should send all events")
+		for _, event := range events {
+			assert.Regexp(t, `^event: update\ndata: \d\ndata: \d$`, event, "Should not interleave events")
+		}
+		assert.Error(t, <-sendAfterClose, "Should fail sending to closed stream")
+	})
+}
+
+func TestSafeRedirect(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/login", func(call *govalin.Call) {
+			if err := call.SafeRedirect(call.QueryParam("returnUrl"), []string{"govalin.dev"}); err != nil {
+				call.Error(err)
+			}
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		status := func(returnURL string) int {
+			// The client reports an error when not following redirects, but still returns the response
+			response, _ := http.Raw().
+				WithOption(httpclient.OPT_FOLLOWLOCATION, false).
+				Get(http.Host + "/login?returnUrl=" + url.QueryEscape(returnURL))
+			return response.StatusCode
+		}
+
+		assert.Equal(t, 302, status("/profile"), "Should redirect to relative path")
+		assert.Equal(t, 302, status("https://govalin.dev/profile"), "Should redirect to allowed host")
+		assert.Equal(t, 400, status("https://evil.dev"), "Should reject unknown host")
+		assert.Equal(t, 400, status("//evil.dev"), "Should reject protocol-relative URL")
+		assert.Equal(t, 400, status("/\\evil.dev"), "Should reject backslash URL")
+		assert.Equal(t, 400, status("javascript:alert(1)"), "Should reject non-http scheme")
+	})
+}
+
+func TestDecodeArray(t *testing.T) {
+	type item struct {
+		ID int `json:"id"`
+	}
+
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Post("/import", func(call *govalin.Call) {
+			sum := 0
+			values, errs := govalin.DecodeArray[item](call)
+			for value := range values {
+				sum += value.ID
+			}
+			if err := <-errs; err != nil {
+				call.Error(err)
+				return
+			}
+			call.Text(strconv.Itoa(sum))
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		post := func(body string) *httpclient.Response {
+			response, _ := http.Raw().Do("POST", http.Host+"/import", nil, strings.NewReader(body))
+			return response
+		}
+
+		body, _ := post(`[{"id":1},{"id":2},{"id":3}]`).ToString()
+		assert.Equal(t, "6", body, "Should decode each array element")
+		assert.Equal(t, 400, post(`{"id":1}`).StatusCode, "Should reject non-array body")
+		assert.Equal(t, 400, post(`[{"id":"one"}]`).StatusCode, "Should report element decode errors")
+
+		response := post(`[{"id":1}` + strings.Repeat(`,{"id":1}`, 1000) + `]`)
+		body, _ = response.ToString()
+		assert.Equal(t, 400, response.StatusCode, "Should reject bodies exceeding the size limit")
+		assert.Contains(t, body, "request body was too big", "Should report the body as too large")
+	})
+}
+
+func TestStatusForError(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Post("/status", func(call *govalin.Call) {
+			body := map[string]string{}
+			call.Text(strconv.Itoa(call.StatusForError(call.BodyAs(&body))))
+		})
+		app.Get("/error", func(call *govalin.Call) {
+			err := fmt.Errorf("unknown error")
+			call.Header("X-Status", strconv.Itoa(call.StatusForError(err)))
+			call.Error(err)
+		})
+		app.Get("/server-error", func(call *govalin.Call) {
+			call.Status(201)
+			err := call.WriteFileFS(fstest.MapFS{"dir/file": &fstest.MapFile{}}, "dir")
+			call.Header("X-Status", strconv.Itoa(call.StatusForError(err)))
+			call.Error(err)
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response, _ := http.Raw().Do("POST", http.Host+"/status", nil, strings.NewReader("{invalid"))
+		body, _ := response.ToString()
+		assert.Equal(t, "400", body, "Should map user errors to bad request without sending it")
+
+		response = http.GetResponse("/error")
+		assert.Equal(t, "500", response.Header.Get("X-Status"), "Should map unknown errors to server error")
+		assert.Equal(t, 500, response.StatusCode, "Should respond with the status given by StatusForError")
+
+		response = http.GetResponse("/server-error")
+		assert.Equal(t, "500", response.Header.Get("X-Status"), "Should map server errors to server error")
+		assert.Equal(t, 500, response.StatusCode, "Should respond with the status given by StatusForError")
+	})
+}
+
+func TestDone(t *testing.T) {
+	aborted := make(chan bool, 1)
+
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/slow", func(call *govalin.Call) {
+			select {
+			case <-time.After(time.Second):
+				aborted <- false
+			case <-call.Done():
+				aborted <- true
+			}
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		_, err := http.Raw().WithOption(httpclient.OPT_TIMEOUT_MS, 50).Get(http.Host + "/slow")
+		assert.Error(t, err, "Should time out on client side")
+		assert.True(t, <-aborted, "Should close done channel when client disconnects")
+	})
+}
+
+func TestCaseInsensitiveQuery(t *testing.T) {
+	govalintesting.HTTPTestUtilWithConfig(func(config *govalin.Config) {
+		config.CaseInsensitiveQuery(true)
+	}, func(app *govalin.App) *govalin.App {
+		app.Get("/query", func(call *govalin.Call) {
+			call.Text(call.QueryParam("page"))
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(t, "2", http.Get("/query?Page=2"), "Should match keys differing in case")
+		assert.Equal(t, "3", http.Get("/query?PAGE=2&page=3"), "Should prefer exact match")
+	})
+
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/query", func(call *govalin.Call) {
+			call.Text(call.QueryParam("page"))
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(t, "", http.Get("/query?Page=2"), "Should be case-sensitive by default")
+	})
+}
+
+func TestOnce(t *testing.T) {
+	computed := 0
+
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		tenant := func(call *govalin.Call) string {
+			value, _ := call.Once("tenant", func() (any, error) {
+				computed++
+				return "govalin", nil
+			})
+			return value.(string)
+		}
+
+		app.Before("/once", func(call *govalin.Call) bool {
+			return tenant(call) == "govalin"
+		})
+		app.Get("/once", func(call *govalin.Call) {
+			call.Text(tenant(call))
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(t, "govalin", http.Get("/once"), "Should return computed value")
+		assert.Equal(t, "govalin", http.Get("/once"), "Should compute value for each request")
+		assert.Equal(t, 2, computed, "Should only compute once per request")
+	})
+}
+
+func TestRequestAndResponseSize(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Post("/size", func(call *govalin.Call) {
+			call.Text("govalin")
+			call.Text(fmt.Sprintf(" %d %d", call.RequestSize(), call.ResponseSize()))
+		})
+		app.Get("/size", func(call *govalin.Call) {
+			call.Text(fmt.Sprintf("%d", call.RequestSize()))
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(t, "govalin 3 7", http.Post("/size", map[string]string{"q": "a"}), "Should report sizes")
+		assert.Equal(t, "0", http.Get("/size"), "Should report empty body")
+	})
+}
+
+func TestCodecRegistry(t *testing.T) {
+	type user struct {
+		Name string `yaml:"name"`
+	}
+
+	govalintesting.HTTPTestUtilWithConfig(func(config *govalin.Config) {
+		config.
+			RegisterDecoder("application/yaml", yaml.Unmarshal).
+			RegisterEncoder("application/yaml", yaml.Marshal)
+	}, func(app *govalin.App) *govalin.App {
+		app.Post("/users", func(call *govalin.Call) {
+			var body user
+			if err := call.BodyInto(&body); err != nil {
+				call.Error(err)
+				return
+			}
+			if err := call.Negotiate(body); err != nil {
+				call.Error(err)
+			}
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response, _ := http.Raw().
+			WithHeader("Content-Type", "application/yaml").
+			WithHeader("Accept", "application/yaml").
+			Do("POST", http.Host+"/users", nil, strings.NewReader("name: gopher\n"))
+		body, _ := response.ToString()
+		assert.Equal(t, "name: gopher\n", body, "Should decode and encode using registered codecs")
+		assert.Equal(t, "application/yaml", response.Header.Get("Content-Type"))
+
+		response, _ = http.Raw().
+			WithHeader("Content-Type", "application/yaml").
+			Do("POST", http.Host+"/users", nil, strings.NewReader("name: gopher\n"))
+		body, _ = response.ToString()
+		assert.Equal(t, `{"Name":"gopher"}`, body, "Should prefer JSON when accepted")
+
+		response, _ = http.Raw().
+			WithHeader("Content-Type", "application/yaml").
+			WithHeader("Accept", "application/yaml, */*;q=0.8").
+			Do("POST", http.Host+"/users", nil, strings.NewReader("name: gopher\n"))
+		body, _ = response.ToString()
+		assert.Equal(t, "name: gopher\n", body, "Should prefer the format with the highest quality")
+
+		response, _ = http.Raw().
+			WithHeader("Content-Type", "application/yaml").
+			WithHeader("Accept", "*/*, application/yaml").
+			Do("POST", http.Host+"/users", nil, strings.NewReader("name: gopher\n"))
+		body, _ = response.ToString()
+		assert.Equal(t, "name: gopher\n", body, "Should prefer the format accepted most specifically")
+
+		response, _ = http.Raw().
+			WithHeader("Content-Type", "application/yaml").
+			WithHeader("Accept", "text/html").
+			Do("POST", http.Host+"/users", nil, strings.NewReader("name: gopher\n"))
+		assert.Equal(t, 406, response.StatusCode, "Should fail when no format is accepted")
+
+		response, _ = http.Raw().
+			WithHeader("Content-Type", "application/yaml").
+			Do("POST", http.Host+"/users", nil, strings.NewReader("name: [gopher"))
+		body, _ = response.ToString()
+		assert.Equal(t, 400, response.StatusCode, "Should fail with bad request when the decoder fails")
+		assert.Contains(t, body, `"field":"request"`, "Should describe the decode error")
+	})
+}
+
+func TestJSONMarshalFailure(t *testing.T) {
+	type invalid struct {
+		Updates chan int
+	}
+
+	serverF := func(app *govalin.App) *govalin.App {
+		app.Get("/invalid", func(call *govalin.Call) {
+			call.Status(201)
+			call.JSON(invalid{Updates: make(chan int)})
+		})
+
+		return app
+	}
+
+	govalintesting.HTTPTestUtil(serverF, func(http govalintesting.GovalinHTTP) {
+		response := http.GetResponse("/invalid")
+		body, _ := response.ToString()
+		assert.Equal(t, 500, response.StatusCode, "Should fail with internal server error")
+		assert.NotContains(t, body, "chan int", "Should not leak the reason in production")
+	})
+
+	govalintesting.HTTPTestUtilWithConfig(func(config *govalin.Config) {
+		config.DevelopmentMode(true)
+	}, serverF, func(http govalintesting.GovalinHTTP) {
+		response := http.GetResponse("/invalid")
+		body, _ := response.ToString()
+		assert.Equal(t, 500, response.StatusCode, "Should fail with internal server error")
+		assert.Contains(t, body, "chan int", "Should include the reason in development mode")
+	})
+}
+
+func TestWriteReader(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/stream", func(call *govalin.Call) {
+			if err := call.WriteReader("text/plain", strings.NewReader("govalin")); err != nil {
+				call.Error(err)
+			}
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response, _ := http.Raw().WithHeader("Range", "bytes=0-2").Get(http.Host + "/stream")
+		body, _ := response.ToString()
+		assert.Equal(t, 200, response.StatusCode, "Should ignore ranges")
+		assert.Equal(t, "govalin", body, "Should stream the full content")
+		assert.Equal(t, "none", response.Header.Get("Accept-Ranges"), "Should advertise lack of range support")
+	})
+}
+
+func TestVerboseDecodeErrors(t *testing.T) {
+	type user struct {
+		Age int
+	}
+
+	serverF := func(app *govalin.App) *govalin.App {
+		app.Post("/users", func(call *govalin.Call) {
+			var body user
+			if err := call.BodyAs(&body); err != nil {
+				call.Error(err)
+				return
+			}
+			call.JSON(body)
+		})
+
+		return app
+	}
+	post := func(http govalintesting.GovalinHTTP, body string) string {
+		response, _ := http.Raw().Do("POST", http.Host+"/users", nil, strings.NewReader(body))
+		responseBody, _ := response.ToString()
+		return responseBody
+	}
+
+	govalintesting.HTTPTestUtil(serverF, func(http govalintesting.GovalinHTTP) {
+		assert.Contains(t, post(http, `{"Age":"old"}`), "user.Age", "Should include field by default")
+		assert.Contains(t, post(http, `{"Age":`), "offset", "Should include syntax error position by default")
+	})
+
+	govalintesting.HTTPTestUtilWithConfig(func(config *govalin.Config) {
+		config.VerboseDecodeErrors(false)
+	}, serverF, func(http govalintesting.GovalinHTTP) {
+		body := post(http, `{"Age":"old"}`)
+		assert.Contains(t, body, "Invalid request body", "Should send generic message")
+		assert.NotContains(t, body, "Age", "Should not leak field names")
+		assert.Contains(t, post(http, `{"Age":`), "Invalid request body", "Should send generic message")
+	})
+}
+
+func TestBodyAsTimeFormat(t *testing.T) {
+	type audit struct {
+		Updated time.Time `json:"updated" time:"02.01.2006 15:04"`
+	}
+	type person struct {
+		audit
+		Name     string      `json:"name"`
+		Dob      time.Time   `json:"dob" time:"2006-01-02"`
+		Holidays []time.Time `json:"holidays"`
+	}
+
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Post("/people", func(call *govalin.Call) {
+			var body person
+			if err := call.BodyAs(&body); err != nil {
+				call.Error(err)
+				return
+			}
+			call.Text(fmt.Sprintf(
+				"%s %s %s %d",
+				body.Name,
+				body.Dob.Format(time.RFC3339),
+				body.Updated.Format(time.RFC3339),
+				len(body.Holidays),
+			))
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		post := func(body string) (int, string) {
+			response, _ := http.Raw().Do("POST", http.Host+"/people", nil, strings.NewReader(body))
+			responseBody, _ := response.ToString()
+			return response.StatusCode, responseBody
+		}
+
+		status, body := post(`{
+			"name": "gopher",
+			"dob": "2009-11-10",
+			"updated": "01.10.2022 12:30",
+			"holidays": ["2022-12-24T00:00:00Z"]
+		}`)
+		assert.Equal(t, 200, status)
+		assert.Equal(
+			t,
+			"gopher 2009-11-10T00:00:00Z 2022-10-01T12:30:00Z 1",
+			body,
+			"Should parse tagged time fields, including promoted fields of embedded structs",
+		)
+
+		status, body = post(`{"name": "gopher", "dob": "10.11.2009"}`)
+		assert.Equal(t, 400, status, "Should fail on time not matching layout")
+		assert.Contains(t, body, "jsonBody.dob", "Should report the failing field")
+	})
+}
+
+func TestProxyRewriteBody(t *testing.T) {
+	upstream := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, req *nethttp.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Encoding", "gzip")
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(nethttp.StatusNotModified)
+			return
+		}
+		gzipWriter := gzip.NewWriter(w)
+		_, _ = gzipWriter.Write([]byte("<body>" + req.URL.Path + "</body>"))
+		_ = gzipWriter.Close()
+	}))
+	defer upstream.Close()
+
+	encodingUpstream := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, req *nethttp.Request) {
+		_, _ = w.Write([]byte(req.Header.Get("Accept-Encoding")))
+	}))
+	defer encodingUpstream.Close()
+
+	govalintesting.HTTPTestUtilWithConfig(func(config *govalin.Config) {
+		config.ResponseFilter(func(call *govalin.Call, contentType string, body []byte) []byte {
+			return []byte(strings.Replace(string(body), "</body>", "<script></script></body>", 1))
+		})
+	}, func(app *govalin.App) *govalin.App {
+		rewritten := func(call *govalin.Call) {
+			if err := call.Proxy(upstream.URL, govalin.RewriteBody()); err != nil {
+				call.Error(err)
+			}
+		}
+		app.Get("/rewritten", rewritten)
+		app.Head("/rewritten", rewritten)
+		app.Get("/encodings", func(call *govalin.Call) {
+			if err := call.Proxy(encodingUpstream.URL, govalin.RewriteBody()); err != nil {
+				call.Error(err)
+			}
+		})
+		app.Get("/untouched", func(call *govalin.Call) {
+			if err := call.Proxy(upstream.URL); err != nil {
+				call.Error(err)
+			}
+		})
+		app.Get("/unreachable", func(call *govalin.Call) {
+			if err := call.Proxy("http://127.0.0.1:1"); err != nil {
+				call.Error(err)
+			}
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(
+			t,
+			"<body>/rewritten<script></script></body>",
+			http.Get("/rewritten"),
+			"Should decompress, filter and recompress gzip bodies",
+		)
+
+		response, _ := http.Raw().Head(http.Host + "/rewritten")
+		assert.Equal(t, 200, response.StatusCode, "Should pass through responses to HEAD requests")
+
+		response, _ = http.Raw().WithHeader("If-None-Match", `"v1"`).Get(http.Host + "/rewritten")
+		assert.Equal(t, 304, response.StatusCode, "Should pass through responses without a body")
+
+		response, _ = http.Raw().WithHeader("Accept-Encoding", "br, gzip;q=0.8").Get(http.Host + "/encodings")
+		body, _ := response.ToString()
+		assert.Equal(t, "gzip;q=0.8", body, "Should only accept rewritable encodings from upstream")
+
+		response, _ = http.Raw().WithHeader("Accept-Encoding", "br").Get(http.Host + "/encodings")
+		body, _ = response.ToString()
+		assert.Equal(t, "identity", body, "Should fall back to identity when no rewritable encoding is accepted")
+
+		assert.Equal(t, "<body>/untouched</body>", http.Get("/untouched"), "Should bypass filters by default")
+		assert.Equal(t, 502, http.GetResponse("/unreachable").StatusCode, "Should fail with bad gateway")
+	})
+}
+
+func TestQueryParamEnum(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/sort", func(call *govalin.Call) {
+			sort, err := call.QueryParamEnum("sort", []string{"asc", "desc"}, "asc")
+			if err != nil {
+				call.Error(err)
+				return
+			}
+			call.Text(sort)
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(t, "desc", http.Get("/sort?sort=desc"), "Should return allowed value")
+		assert.Equal(t, "asc", http.Get("/sort"), "Should use default on absent value")
+
+		response := http.GetResponse("/sort?sort=random")
+		body, _ := response.ToString()
+		assert.Equal(t, 400, response.StatusCode, "Should fail with bad request on value not allowed")
+		assert.Contains(t, body, "Expected one of 'asc', 'desc'", "Should list allowed values")
+	})
+}
+
+func TestJSONEscapeHTML(t *testing.T) {
+	serverF := func(app *govalin.App) *govalin.App {
+		app.Get("/json", func(call *govalin.Call) {
+			call.JSON(map[string]string{"url": "/search?q=go&sort=asc"})
+		})
+
+		return app
+	}
+
+	govalintesting.HTTPTestUtil(serverF, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(t, `{"url":"/search?q=go\u0026sort=asc"}`, http.Get("/json"), "Should escape HTML by default")
+	})
+
+	govalintesting.HTTPTestUtilWithConfig(func(config *govalin.Config) {
+		config.JSONEscapeHTML(false)
+	}, serverF, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(t, `{"url":"/search?q=go&sort=asc"}`, http.Get("/json"), "Should not escape HTML when disabled")
+	})
+}
+
+func TestVariant(t *testing.T) {
+	resolved := 0
+
+	govalintesting.HTTPTestUtilWithConfig(func(config *govalin.Config) {
+		config.VariantResolver(func(call *govalin.Call) map[string]string {
+			resolved++
+			if call.Header("X-Beta") == "true" {
+				return map[string]string{"checkout": "new"}
+			}
+			return map[string]string{"checkout": "old"}
+		})
+	}, func(app *govalin.App) *govalin.App {
+		app.Before("/checkout", func(call *govalin.Call) bool {
+			return call.Variant("checkout") != ""
+		})
+		app.Get("/checkout", func(call *govalin.Call) {
+			call.Text(call.Variant("checkout") + call.Variant("unknown"))
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(t, "old", http.Get("/checkout"), "Should resolve variant")
+
+		response, _ := http.Raw().WithHeader("X-Beta", "true").Get(http.Host + "/checkout")
+		body, _ := response.ToString()
+		assert.Equal(t, "new", body, "Should resolve variant from request")
+		assert.Equal(t, 2, resolved, "Should resolve variants once per request")
+	})
+}
+
+func TestMultipart(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/export", func(call *govalin.Call) {
+			parts := call.Multipart()
+			defer parts.Close()
+
+			part, _ := parts.NextPart(nethttp.Header{"Content-Type": {"application/json"}})
+			_, _ = part.Write([]byte(`{"name":"govalin"}`))
+
+			part, _ = parts.NextPart(nethttp.Header{"Content-Type": {"application/octet-stream"}})
+			_, _ = part.Write([]byte{0x67, 0x6f})
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response := http.GetResponse("/export")
+		mediaType, params, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+		assert.NoError(t, err)
+		assert.Equal(t, "multipart/mixed", mediaType, "Should set multipart content type")
+
+		reader := multipart.NewReader(response.Body, params["boundary"])
+
+		part, err := reader.NextPart()
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(part)
+		assert.Equal(t, "application/json", part.Header.Get("Content-Type"), "Should write part headers")
+		assert.Equal(t, `{"name":"govalin"}`, string(body), "Should write part body")
+
+		part, err = reader.NextPart()
+		assert.NoError(t, err)
+		body, _ = io.ReadAll(part)
+		assert.Equal(t, "go", string(body), "Should write binary part body")
+
+		_, err = reader.NextPart()
+		assert.ErrorIs(t, err, io.EOF, "Should terminate the response")
+	})
+}
+
+func TestLogger(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	restoreLogger := govalin.ReplaceLogger(zap.New(core).Sugar())
+	defer restoreLogger()
+
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Before("/users/{id}", func(call *govalin.Call) bool {
+			call.AddLogFields("userId", call.PathParam("id"))
+			return true
+		})
+		app.Get("/users/{id}", func(call *govalin.Call) {
+			call.Logger().Info("fetched user")
+			call.Text("govalin")
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response, _ := http.Raw().WithHeader("X-Request-Id", "abc123").Get(http.Host + "/users/42")
+		assert.Equal(t, 200, response.StatusCode)
+
+		entries := logs.FilterMessage("fetched user").All()
+		assert.Len(t, entries, 1, "Should log using the request scoped logger")
+		assert.Equal(
+			t,
+			map[string]any{"method": "GET", "path": "/users/42", "requestId": "abc123", "userId": "42"},
+			entries[0].ContextMap(),
+			"Should include the request fields and the added fields",
+		)
+	})
+}
+
+func TestHijack(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/upgrade", func(call *govalin.Call) {
+			if _, ok := (*call.Raw.W).(interface{ Unwrap() nethttp.ResponseWriter }); !ok {
+				t.Error("Should expose the underlying response writer")
+			}
+
+			hijacker, ok := (*call.Raw.W).(nethttp.Hijacker)
+			if !ok {
+				call.Status(500)
+				call.Text("not hijackable")
+				return
+			}
+
+			conn, buffer, err := hijacker.Hijack()
+			if err != nil {
+				call.Status(500)
+				call.Text(err.Error())
+				return
+			}
+			defer conn.Close()
+
+			_, _ = buffer.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+			_ = buffer.Flush()
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(t, "hijacked", http.Get("/upgrade"), "Should let handlers hijack the connection")
+	})
+}
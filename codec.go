@@ -0,0 +1,89 @@
+package govalin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkkummermo/govalin/internal/negotiation"
+)
+
+// DecoderFunc deserializes the raw body data into given object.
+type DecoderFunc func(data []byte, obj any) error
+
+// EncoderFunc serializes given object into the raw response body.
+type EncoderFunc func(obj any) ([]byte, error)
+
+type encoder struct {
+	contentType string
+	encode      EncoderFunc
+}
+
+// Register a body decoder for a content type
+//
+// RegisterDecoder registers a decoder used by BodyInto for request bodies of given
+// content type, such as "application/yaml". Registered decoders take precedence over
+// the built-in JSON and CSV decoding, allowing them to be replaced.
+func (config *Config) RegisterDecoder(contentType string, decoder DecoderFunc) *Config {
+	config.decoders[strings.ToLower(contentType)] = decoder
+	return config
+}
+
+// Register a response encoder for a content type
+//
+// RegisterEncoder registers an encoder used by Negotiate to serialize responses for
+// clients accepting given content type. Encoders are considered after JSON, in the
+// order they were registered.
+func (config *Config) RegisterEncoder(contentType string, encode EncoderFunc) *Config {
+	config.encoders = append(config.encoders, encoder{contentType: contentType, encode: encode})
+	return config
+}
+
+// Send obj in the format preferred by the client
+//
+// Negotiate serializes given object using JSON or a registered encoder, picking the
+// one preferred by the Accept header of the request, and writes it to the response.
+// Content types are ranked by their quality, and then by how specifically they are
+// accepted, e.g. preferring "application/yaml" over JSON accepted through "*/*". JSON
+// is picked when otherwise equally preferred, followed by the encoders in the order
+// they were registered. Returns a not acceptable error without writing anything if
+// no format is accepted.
+func (call *Call) Negotiate(obj any) error {
+	accept := strings.Join(call.req.Header.Values("Accept"), ",")
+
+	var preferred *encoder
+	bestQuality, bestSpecificity := negotiation.Preference(accept, "application/json")
+
+	contentTypes := []string{"application/json"}
+	for i := range call.config.encoders {
+		candidate := &call.config.encoders[i]
+		contentTypes = append(contentTypes, candidate.contentType)
+
+		quality, specificity := negotiation.Preference(accept, candidate.contentType)
+		if quality > bestQuality || (quality == bestQuality && specificity > bestSpecificity) {
+			preferred = candidate
+			bestQuality, bestSpecificity = quality, specificity
+		}
+	}
+
+	if bestQuality == 0 {
+		return newErrorFromType(
+			notAcceptableError,
+			fmt.Errorf("accepted content types are %s", strings.Join(contentTypes, ", ")),
+		)
+	}
+
+	if preferred == nil {
+		call.JSON(obj)
+		return nil
+	}
+
+	body, err := preferred.encode(obj)
+	if err != nil {
+		return newErrorFromType(serverError, fmt.Errorf("failed to encode response as %s. %w", preferred.contentType, err))
+	}
+
+	call.setContentTypeIfAbsent(preferred.contentType)
+	call.writeBody(body)
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package govalin
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ResponseFilterFunc transforms the body of a response before it is written.
+type ResponseFilterFunc func(call *Call, contentType string, body []byte) []byte
+
+// VariantResolverFunc resolves the feature variants assigned to the request of given call.
+type VariantResolverFunc func(call *Call) map[string]string
+
+// ConfigFunc configures a govalin App when creating it.
+type ConfigFunc func(config *Config)
+
+const (
+	// Default max nesting depth of JSON bodies.
+	defaultMaxJSONDepth = 64
+)
+
+// Config holds the configuration of a govalin App.
+type Config struct {
+	trustedProxies       []*net.IPNet
+	maxJSONDepth         int
+	maxJSONElements      int
+	cors                 *CORSConfig
+	responseFilters      []ResponseFilterFunc
+	caseInsensitiveQuery bool
+	decoders             map[string]DecoderFunc
+	encoders             []encoder
+	developmentMode      bool
+	verboseDecodeErrors  bool
+	maxRequests          int
+	jsonEscapeHTML       bool
+	variantResolver      VariantResolverFunc
+	errors               []error
+}
+
+func newDefaultConfig() *Config {
+	return &Config{
+		trustedProxies:      []*net.IPNet{},
+		maxJSONDepth:        defaultMaxJSONDepth,
+		maxJSONElements:     0,
+		responseFilters:     []ResponseFilterFunc{},
+		decoders:            map[string]DecoderFunc{},
+		encoders:            []encoder{},
+		verboseDecodeErrors: true,
+		jsonEscapeHTML:      true,
+	}
+}
+
+// Set proxies trusted to forward client information
+//
+// TrustedProxies takes IPs or CIDR ranges of proxies whose Forwarded and
+// X-Forwarded-* headers are trusted when resolving the client IP and scheme.
+// By default no proxies are trusted and the headers are ignored. Invalid proxies
+// make Start fail.
+func (config *Config) TrustedProxies(proxies ...string) *Config {
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			config.addError(fmt.Errorf("invalid trusted proxy '%s'. %w", proxy, err))
+			continue
+		}
+
+		config.trustedProxies = append(config.trustedProxies, ipNet)
+	}
+
+	return config
+}
+
+// addError records an error in the configuration, failing Start.
+func (config *Config) addError(err error) {
+	log.Errorf("Invalid config. %v", err)
+	config.errors = append(config.errors, err)
+}
+
+// Set max nesting depth of JSON bodies
+//
+// MaxJSONDepth sets the max nesting depth of objects and arrays allowed in JSON
+// bodies deserialized by BodyAs. Bodies nested deeper are rejected with a bad
+// request error, protecting against JSON bombs. Defaults to 64, 0 disables the limit.
+func (config *Config) MaxJSONDepth(maxDepth int) *Config {
+	config.maxJSONDepth = maxDepth
+	return config
+}
+
+// Set max number of elements in JSON objects and arrays
+//
+// MaxJSONElements sets the max number of keys in a single JSON object or elements
+// in a single JSON array allowed in JSON bodies deserialized by BodyAs. Bodies with
+// larger objects or arrays are rejected with a bad request error. Defaults to 0,
+// which disables the limit.
+func (config *Config) MaxJSONElements(maxElements int) *Config {
+	config.maxJSONElements = maxElements
+	return config
+}
+
+// Enable CORS
+//
+// CORS enables handling of cross-origin requests, answering preflight requests
+// and setting the CORS headers on responses. The CORS configuration can be
+// adjusted using the given config function.
+func (config *Config) CORS(corsFunc func(cors *CORSConfig)) *Config {
+	config.cors = newDefaultCORSConfig()
+	corsFunc(config.cors)
+
+	return config
+}
+
+// Add a response filter
+//
+// ResponseFilter adds a filter which can transform the body of responses sent using
+// Text, HTML or JSON before it is written, e.g. injecting a nonce into HTML or wrapping
+// JSON in an envelope. The filter receives the content type of the response and may
+// set headers on the response. Multiple filters are run in the order they were added.
+// Streamed responses, such as JSONArray, bypass the filters.
+func (config *Config) ResponseFilter(responseFilter ResponseFilterFunc) *Config {
+	config.responseFilters = append(config.responseFilters, responseFilter)
+	return config
+}
+
+// Enable case-insensitive query params
+//
+// CaseInsensitiveQuery makes query param lookups fall back to keys differing only in
+// case, e.g. making QueryParam("page") match ?Page=1, for legacy clients. Exact matches
+// are always preferred. Disabled by default.
+func (config *Config) CaseInsensitiveQuery(caseInsensitive bool) *Config {
+	config.caseInsensitiveQuery = caseInsensitive
+	return config
+}
+
+// Enable development mode
+//
+// DevelopmentMode makes failures caused by programming errors loud instead of silent,
+// e.g. by including the reason in the 500 response sent when Call.JSON fails to
+// serialize an object. Should not be enabled in production, as it may leak internals.
+func (config *Config) DevelopmentMode(developmentMode bool) *Config {
+	config.developmentMode = developmentMode
+	return config
+}
+
+// Set verbosity of JSON decode errors
+//
+// VerboseDecodeErrors controls whether the bad request responses sent by Call.Error for
+// bodies failing to decode as JSON include details such as the failing field and its
+// expected type, or the offset of a syntax error. When disabled, a generic message is
+// sent to the client while the details are logged, avoiding leaking internal field
+// names. Enabled by default.
+func (config *Config) VerboseDecodeErrors(verbose bool) *Config {
+	config.verboseDecodeErrors = verbose
+	return config
+}
+
+// Set max number of concurrently handled requests
+//
+// MaxConcurrentRequests bounds the number of requests handled at the same time. Requests
+// exceeding the limit are rejected immediately with a 503 Service Unavailable and a
+// Retry-After header instead of being queued, shedding load during spikes. Defaults to 0,
+// which disables the limit.
+func (config *Config) MaxConcurrentRequests(maxRequests int) *Config {
+	config.maxRequests = maxRequests
+	return config
+}
+
+// Set whether JSON responses escape HTML characters
+//
+// JSONEscapeHTML controls whether <, > and & in JSON responses sent by Call.JSON and
+// Call.JSONArray are escaped as \u003c, \u003e and \u0026, which keeps the JSON safe
+// to embed in HTML. APIs whose responses never land in HTML can disable the escaping
+// for more readable responses. Enabled by default.
+func (config *Config) JSONEscapeHTML(escapeHTML bool) *Config {
+	config.jsonEscapeHTML = escapeHTML
+	return config
+}
+
+// Set the resolver of feature variants
+//
+// VariantResolver sets a resolver which is run once for every request before any
+// handlers, assigning feature variants to the request, e.g. from a cookie, header or
+// user ID for A/B testing or gradual rollouts. Handlers get the assigned variants
+// using Call.Variant.
+func (config *Config) VariantResolver(variantResolver VariantResolverFunc) *Config {
+	config.variantResolver = variantResolver
+	return config
+}
+
+// isTrustedProxy checks whether given IP belongs to a trusted proxy.
+func (config *Config) isTrustedProxy(ip string) bool {
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return false
+	}
+
+	for _, trustedProxy := range config.trustedProxies {
+		if trustedProxy.Contains(parsedIP) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,67 @@
+package govalin
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// setValueFromString converts given string into the type of given value and sets it.
+func setValueFromString(value reflect.Value, str string) error {
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(str)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(str)
+		if err != nil {
+			return err
+		}
+		value.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(str, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(str, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(str, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(parsed)
+	case reflect.Pointer:
+		pointerValue := reflect.New(value.Type().Elem())
+		if err := setValueFromString(pointerValue.Elem(), str); err != nil {
+			return err
+		}
+		value.Set(pointerValue)
+	default:
+		return fmt.Errorf("unsupported type '%s'", value.Type())
+	}
+
+	return nil
+}
+
+// setValuesFromStrings sets given slice value to given strings converted to the element type
+// of the slice. Values of any other type are set to the first string.
+func setValuesFromStrings(value reflect.Value, strs []string) error {
+	if value.Kind() != reflect.Slice || value.Type().Elem().Kind() == reflect.Uint8 {
+		return setValueFromString(value, strs[0])
+	}
+
+	sliceValue := reflect.MakeSlice(value.Type(), len(strs), len(strs))
+	for i, str := range strs {
+		if err := setValueFromString(sliceValue.Index(i), str); err != nil {
+			return err
+		}
+	}
+	value.Set(sliceValue)
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package govalin
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Default duration browsers may cache preflight responses.
+	defaultCORSMaxAge = 10 * time.Minute
+)
+
+// CORSConfig holds the CORS configuration of a govalin App.
+type CORSConfig struct {
+	allowedOrigins []string
+	allowedMethods []string
+	allowedHeaders []string
+	maxAge         time.Duration
+}
+
+func newDefaultCORSConfig() *CORSConfig {
+	return &CORSConfig{
+		allowedOrigins: []string{"*"},
+		allowedMethods: []string{
+			http.MethodGet,
+			http.MethodHead,
+			http.MethodPost,
+			http.MethodPut,
+			http.MethodPatch,
+			http.MethodDelete,
+		},
+		allowedHeaders: []string{},
+		maxAge:         defaultCORSMaxAge,
+	}
+}
+
+// Set allowed origins
+//
+// AllowOrigins sets the origins allowed to make cross-origin requests. Use "*"
+// to allow any origin, which is the default.
+func (cors *CORSConfig) AllowOrigins(origins ...string) *CORSConfig {
+	cors.allowedOrigins = origins
+	return cors
+}
+
+// Set allowed methods
+//
+// AllowMethods sets the methods allowed in cross-origin requests. Defaults to
+// GET, HEAD, POST, PUT, PATCH and DELETE.
+func (cors *CORSConfig) AllowMethods(methods ...string) *CORSConfig {
+	cors.allowedMethods = methods
+	return cors
+}
+
+// Set allowed headers
+//
+// AllowHeaders sets the request headers allowed in cross-origin requests. If no
+// headers are given, the headers requested by the preflight request are allowed.
+func (cors *CORSConfig) AllowHeaders(headers ...string) *CORSConfig {
+	cors.allowedHeaders = headers
+	return cors
+}
+
+// Set max age of preflight responses
+//
+// MaxAge sets how long browsers may cache the response of a preflight request
+// using the Access-Control-Max-Age header, saving a preflight request for every
+// cross-origin request. Defaults to 10 minutes, 0 omits the header.
+func (cors *CORSConfig) MaxAge(maxAge time.Duration) *CORSConfig {
+	cors.maxAge = maxAge
+	return cors
+}
+
+// handle sets the CORS headers of the response. Returns true if the request was
+// a preflight request which has been answered and needs no further handling.
+func (cors *CORSConfig) handle(call *Call) bool {
+	origin := call.req.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+
+	allowedOrigin, allowed := cors.allowedOrigin(origin)
+	if allowedOrigin != "*" {
+		call.w.Header().Add("Vary", "Origin")
+	}
+
+	isPreflight := call.req.Method == http.MethodOptions && call.req.Header.Get("Access-Control-Request-Method") != ""
+	if isPreflight {
+		call.w.Header().Add("Vary", "Access-Control-Request-Method, Access-Control-Request-Headers")
+	}
+
+	if !allowed {
+		return false
+	}
+
+	call.w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
+
+	if !isPreflight {
+		return false
+	}
+
+	call.w.Header().Set("Access-Control-Allow-Methods", strings.Join(cors.allowedMethods, ", "))
+
+	allowedHeaders := strings.Join(cors.allowedHeaders, ", ")
+	if len(cors.allowedHeaders) == 0 {
+		allowedHeaders = call.req.Header.Get("Access-Control-Request-Headers")
+	}
+	if allowedHeaders != "" {
+		call.w.Header().Set("Access-Control-Allow-Headers", allowedHeaders)
+	}
+
+	if cors.maxAge > 0 {
+		call.w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(cors.maxAge.Seconds())))
+	}
+
+	call.Status(http.StatusNoContent)
+	call.sendStatusOrDefault()
+
+	return true
+}
+
+// allowedOrigin returns the value to use in the Access-Control-Allow-Origin header
+// for given origin, and whether the origin is allowed.
+func (cors *CORSConfig) allowedOrigin(origin string) (string, bool) {
+	for _, allowedOrigin := range cors.allowedOrigins {
+		if allowedOrigin == "*" {
+			return "*", true
+		}
+
+		if strings.EqualFold(allowedOrigin, origin) {
+			return origin, true
+		}
+	}
+
+	return "", false
+}
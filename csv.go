@@ -0,0 +1,112 @@
+package govalin
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/pkkummermo/govalin/internal/validation"
+)
+
+// decodeCSV decodes given CSV data into a *[][]string or a pointer to a slice of structs.
+// When decoding into structs the first row is used as a header row mapping columns to fields.
+func decodeCSV(data []byte, obj any) error {
+	objValue := reflect.ValueOf(obj)
+	if objValue.Kind() != reflect.Pointer || objValue.Elem().Kind() != reflect.Slice {
+		return newErrorFromType(serverError, fmt.Errorf("must provide a pointer to a slice to correctly decode CSV body"))
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return validation.NewError(
+			validation.NewErrorResponse(
+				http.StatusBadRequest,
+				validation.NewParameterErrorDetail("csvBody", "Invalid CSV found in body. "+err.Error()),
+			),
+		)
+	}
+
+	if rows, ok := obj.(*[][]string); ok {
+		*rows = records
+		return nil
+	}
+
+	sliceValue := objValue.Elem()
+	structType := sliceValue.Type().Elem()
+	if structType.Kind() != reflect.Struct {
+		return newErrorFromType(serverError, fmt.Errorf("must provide a pointer to a slice of structs to decode CSV body"))
+	}
+
+	if len(records) == 0 {
+		sliceValue.Set(reflect.MakeSlice(sliceValue.Type(), 0, 0))
+		return nil
+	}
+
+	fieldIndexes := csvFieldIndexes(structType, records[0])
+	rowValues := reflect.MakeSlice(sliceValue.Type(), 0, len(records)-1)
+	errorDetails := []validation.ErrorDetail{}
+
+	for rowIndex, record := range records[1:] {
+		rowValue := reflect.New(structType).Elem()
+
+		for column, value := range record {
+			fieldIndex, ok := fieldIndexes[column]
+			if !ok {
+				continue
+			}
+
+			if convertErr := setValueFromString(rowValue.Field(fieldIndex), value); convertErr != nil {
+				errorDetails = append(errorDetails, validation.NewParameterErrorDetail(
+					// Rows are numbered by their line in the CSV body, the header being line 1
+					fmt.Sprintf("csv[%d].%s", rowIndex+2, records[0][column]),
+					fmt.Sprintf("Incorrect type. '%s' is not of type '%s'", value, rowValue.Field(fieldIndex).Type()),
+				))
+			}
+		}
+
+		rowValues = reflect.Append(rowValues, rowValue)
+	}
+
+	if len(errorDetails) > 0 {
+		return validation.NewError(validation.NewErrorResponse(http.StatusBadRequest, errorDetails...))
+	}
+
+	sliceValue.Set(rowValues)
+
+	return nil
+}
+
+// csvFieldIndexes maps the column indexes of given header row to the matching
+// field indexes of given struct type.
+func csvFieldIndexes(structType reflect.Type, header []string) map[int]int {
+	fieldIndexes := map[int]int{}
+
+	for column, name := range header {
+		name = strings.TrimSpace(name)
+
+		for i := 0; i < structType.NumField(); i++ {
+			field := structType.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			if tag, ok := field.Tag.Lookup("csv"); ok {
+				if tag == name {
+					fieldIndexes[column] = i
+					break
+				}
+				continue
+			}
+
+			if strings.EqualFold(field.Name, name) {
+				fieldIndexes[column] = i
+				break
+			}
+		}
+	}
+
+	return fieldIndexes
+}
@@ -0,0 +1,118 @@
+package govalin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/pkkummermo/govalin/internal/validation"
+)
+
+// Decode a JSON array body element by element
+//
+// DecodeArray streams the elements of a top-level JSON array body one at a time, sending
+// each decoded element on the returned value channel without holding the whole array in
+// memory. This allows processing huge bulk imports with bounded memory. The value channel
+// is closed when the array has been fully decoded, after which the error channel yields
+// the first error that occurred, or nil on success. Decoding stops if the request context
+// is cancelled, e.g. by the client disconnecting. The body size limit still applies unless
+// the body is unlimited using Call.UnlimitedBody, yielding a bad request error when exceeded.
+//
+//	values, errs := govalin.DecodeArray[Item](call)
+//	for value := range values {
+//		// Process value
+//	}
+//	if err := <-errs; err != nil {
+//		call.Error(err)
+//	}
+func DecodeArray[T any](call *Call) (<-chan T, <-chan error) {
+	values := make(chan T)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(values)
+
+		decoder := json.NewDecoder(call.bodyReader())
+
+		token, err := decoder.Token()
+		if errors.Is(err, errBodyTooLarge) {
+			errs <- newErrorFromType(userError, err)
+			return
+		}
+
+		if err != nil || token != json.Delim('[') {
+			errs <- validation.NewError(
+				validation.NewErrorResponse(
+					http.StatusBadRequest,
+					validation.NewParameterErrorDetail("jsonBody", "Expected a JSON array in body"),
+				),
+			)
+			return
+		}
+
+		for decoder.More() {
+			var value T
+			if err := decoder.Decode(&value); err != nil {
+				errs <- newErrorFromType(userError, err)
+				return
+			}
+
+			select {
+			case values <- value:
+			case <-call.req.Context().Done():
+				errs <- call.req.Context().Err()
+				return
+			}
+		}
+
+		if _, err := decoder.Token(); err != nil {
+			errs <- newErrorFromType(userError, err)
+		}
+	}()
+
+	return values, errs
+}
+
+// errBodyTooLarge is returned when reading a body exceeding the max body read size.
+var errBodyTooLarge = errors.New("request body was too big, could not read full body")
+
+// bodyReader returns a reader of the request body, honoring the body size limit.
+func (call *Call) bodyReader() io.Reader {
+	if call.bodyBytes != nil {
+		return bytes.NewReader(call.bodyBytes)
+	}
+
+	if call.unlimitedBody {
+		return call.req.Body
+	}
+
+	return &limitedBodyReader{reader: io.LimitReader(call.req.Body, maxBodyReadSize+1)}
+}
+
+// limitedBodyReader reads a body up to the max body read size, failing with errBodyTooLarge
+// instead of silently truncating bodies exceeding it. Given reader must be limited to one
+// byte more than the max body read size, allowing too large bodies to be detected.
+type limitedBodyReader struct {
+	reader    io.Reader
+	bytesRead int64
+	exceeded  bool
+}
+
+func (limitedReader *limitedBodyReader) Read(bytes []byte) (int, error) {
+	if limitedReader.exceeded {
+		return 0, errBodyTooLarge
+	}
+
+	read, err := limitedReader.reader.Read(bytes)
+	limitedReader.bytesRead += int64(read)
+
+	if excess := limitedReader.bytesRead - maxBodyReadSize; excess > 0 {
+		limitedReader.exceeded = true
+		return read - int(excess), errBodyTooLarge
+	}
+
+	return read, err
+}
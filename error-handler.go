@@ -0,0 +1,107 @@
+package govalin
+
+import (
+	"strings"
+
+	"github.com/pkkummermo/govalin/internal/routing"
+)
+
+// errorHandler is an error handler scoped to the requests of a route.
+type errorHandler struct {
+	fragment string
+	// Matches the route itself
+	routeMatcher routing.PathMatcher
+	// Matches any path below the route
+	subPathMatcher routing.PathMatcher
+	handle         ErrorHandlerFunc
+}
+
+// Add an error handler
+//
+// Add an error handler receiving the errors passed to Call.Error, replacing the default
+// error responses. The handler applies to the requests of the route hierarchy it's
+// registered in, including routes with path params, or the whole app when registered
+// outside of any route. When multiple handlers apply, the one registered in the most
+// specific route is used, e.g. letting an /api route respond with JSON errors while the
+// rest of the app renders error pages. The handler also receives the errors of requests
+// to missing paths and of requests rejected by the max concurrent requests limit.
+func (server *App) ErrorHandler(handle ErrorHandlerFunc) *App {
+	fragment := server.currentFragment
+
+	routeMatcher, err := routing.NewPathMatcherFromString(fragment)
+	if err != nil {
+		server.addRegistrationError(err)
+		return server
+	}
+
+	subPathMatcher, err := routing.NewPathMatcherFromString(strings.TrimSuffix(fragment, "/") + "/*")
+	if err != nil {
+		server.addRegistrationError(err)
+		return server
+	}
+
+	newHandler := errorHandler{
+		fragment:       fragment,
+		routeMatcher:   routeMatcher,
+		subPathMatcher: subPathMatcher,
+		handle:         handle,
+	}
+
+	for i := range server.errorHandlers {
+		if server.errorHandlers[i].fragment == fragment {
+			server.errorHandlers[i] = newHandler
+			return server
+		}
+	}
+	server.errorHandlers = append(server.errorHandlers, newHandler)
+
+	return server
+}
+
+// findErrorHandler returns the error handler registered for the most specific route matching given path.
+func (server *App) findErrorHandler(path string) ErrorHandlerFunc {
+	var found *errorHandler
+
+	for i := range server.errorHandlers {
+		candidate := &server.errorHandlers[i]
+		if !candidate.matches(path) {
+			continue
+		}
+
+		if found == nil || candidate.isMoreSpecificThan(found) {
+			found = candidate
+		}
+	}
+
+	if found == nil {
+		return nil
+	}
+
+	return found.handle
+}
+
+// matches checks whether given path is within the route of the error handler.
+func (handler *errorHandler) matches(path string) bool {
+	if handler.fragment == "" {
+		return true
+	}
+
+	return handler.routeMatcher.MatchesURL(path) || handler.subPathMatcher.MatchesURL(path)
+}
+
+// isMoreSpecificThan checks whether the route of the error handler is nested deeper than
+// the route of given error handler.
+func (handler *errorHandler) isMoreSpecificThan(other *errorHandler) bool {
+	segments := len(strings.FieldsFunc(handler.fragment, isSlash))
+	otherSegments := len(strings.FieldsFunc(other.fragment, isSlash))
+
+	if segments != otherSegments {
+		return segments > otherSegments
+	}
+
+	return len(handler.fragment) > len(other.fragment)
+}
+
+func isSlash(char rune) bool {
+	return char == '/'
+}
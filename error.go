@@ -14,8 +14,13 @@ func (err *govalinError) Error() string {
 }
 
 const (
-	serverError govalinErrorType = "Server error"
-	userError   govalinErrorType = "User error"
+	serverError               govalinErrorType = "Server error"
+	userError                 govalinErrorType = "User error"
+	unsupportedMediaTypeError govalinErrorType = "Unsupported media type error"
+	notAcceptableError        govalinErrorType = "Not acceptable error"
+	notFoundError             govalinErrorType = "Not found error"
+	badGatewayError           govalinErrorType = "Bad gateway error"
+	serviceUnavailableError   govalinErrorType = "Service unavailable error"
 )
 
 func newErrorFromType(errorType govalinErrorType, err error) error {
@@ -0,0 +1,14 @@
+package govalin
+
+import "go.uber.org/zap"
+
+// ReplaceLogger replaces the logger of the package, returning a function restoring
+// the original logger. Used by tests asserting on logged entries.
+func ReplaceLogger(logger *zap.SugaredLogger) func() {
+	original := log
+	log = logger
+
+	return func() {
+		log = original
+	}
+}
@@ -0,0 +1,67 @@
+package govalin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+)
+
+// Write a file from given filesystem to the response
+//
+// WriteFileFS serves the file with given name from given filesystem, e.g. an embed.FS.
+// The content type is detected from the file extension, falling back to sniffing the
+// content. Since embedded files have no modification time, an ETag is synthesized from
+// the content of the file, supporting conditional requests using If-None-Match. Returns
+// a not found error, which is handled as a 404 by Call.Error, if the file doesn't exist.
+func (call *Call) WriteFileFS(fsys fs.FS, name string) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return newErrorFromType(notFoundError, fmt.Errorf("the file '%s' doesn't exist", name))
+		}
+
+		return newErrorFromType(serverError, fmt.Errorf("failed to read file '%s'. %w", name, err))
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	call.setContentTypeIfAbsent(contentType)
+
+	call.w.Header().Set("Accept-Ranges", "bytes")
+
+	hash := sha256.Sum256(data)
+	call.w.Header().Set("ETag", `"`+hex.EncodeToString(hash[:16])+`"`)
+
+	http.ServeContent(call.w, call.req, name, time.Time{}, bytes.NewReader(data))
+	call.statusWritten = true
+
+	return nil
+}
+
+// Stream the content of a reader to the response
+//
+// WriteReader sets the content type of the response, unless already set, and copies
+// the content of given reader to the response as it's read. As the content can't be
+// seeked, ranged requests aren't supported, which is advertised to clients using
+// Accept-Ranges: none. Use WriteFileFS for content supporting ranges.
+func (call *Call) WriteReader(contentType string, reader io.Reader) error {
+	call.setContentTypeIfAbsent(contentType)
+	call.w.Header().Set("Accept-Ranges", "none")
+	call.sendStatusOrDefault()
+
+	if _, err := io.Copy(call.w, reader); err != nil {
+		return newErrorFromType(serverError, fmt.Errorf("failed to stream response. %w", err))
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package govalin
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pkkummermo/govalin/internal/proxy"
+)
+
+// Get the IP of the client
+//
+// Returns the IP of the client sending the request. If the request was sent by a
+// trusted proxy, the client IP is resolved from the Forwarded header (RFC 7239),
+// falling back to the X-Forwarded-For header. The forwarding chain is walked from
+// the closest proxy, returning the first node which is not a trusted proxy.
+func (call *Call) IP() string {
+	remoteIP := proxy.StripPort(call.req.RemoteAddr)
+	if !call.isFromTrustedProxy() {
+		return remoteIP
+	}
+
+	forwardedFor := call.forwardedFor()
+	for i := len(forwardedFor) - 1; i >= 0; i-- {
+		if !call.config.isTrustedProxy(forwardedFor[i]) {
+			return forwardedFor[i]
+		}
+	}
+
+	if len(forwardedFor) > 0 {
+		return forwardedFor[0]
+	}
+
+	return remoteIP
+}
+
+// Get the scheme of the request
+//
+// Returns the scheme ("http" or "https") used by the client. If the request was sent
+// by a trusted proxy, the scheme is resolved from the proto parameter of the Forwarded
+// header (RFC 7239), falling back to the X-Forwarded-Proto header. Only the values
+// added by trusted proxies are used, as values further along the chain may have been
+// sent by the client, and values other than "http" and "https" are ignored.
+func (call *Call) Scheme() string {
+	scheme := "http"
+	if call.req.TLS != nil {
+		scheme = "https"
+	}
+
+	if !call.isFromTrustedProxy() {
+		return scheme
+	}
+
+	if forwarded := call.trustedForwardedElements(); len(forwarded) > 0 {
+		for _, element := range forwarded {
+			if isValidScheme(element.Proto) {
+				return element.Proto
+			}
+		}
+
+		return scheme
+	}
+
+	forwardedProto := strings.ToLower(lastHeaderListValue(call.req.Header, "X-Forwarded-Proto"))
+	if isValidScheme(forwardedProto) {
+		return forwardedProto
+	}
+
+	return scheme
+}
+
+// Get the base URL of the request
+//
+// Returns the scheme and host used by the client, e.g. "https://govalin.dev", for
+// building absolute links back to the app. If the request was sent by a trusted proxy,
+// the scheme is resolved like Scheme and the host from the host parameter of the
+// Forwarded header (RFC 7239), falling back to the X-Forwarded-Host header.
+func (call *Call) BaseURL() string {
+	return call.Scheme() + "://" + call.host()
+}
+
+// Get the full URL of the request
+//
+// Returns the full URL used by the client, consisting of the base URL given by BaseURL
+// followed by the path and query of the request.
+func (call *Call) FullURL() string {
+	return call.BaseURL() + call.req.URL.RequestURI()
+}
+
+// host returns the host used by the client, honoring forwarding headers from trusted proxies.
+// Like Scheme, only the values added by trusted proxies are used.
+func (call *Call) host() string {
+	if !call.isFromTrustedProxy() {
+		return call.req.Host
+	}
+
+	if forwarded := call.trustedForwardedElements(); len(forwarded) > 0 {
+		for _, element := range forwarded {
+			if element.Host != "" {
+				return element.Host
+			}
+		}
+
+		return call.req.Host
+	}
+
+	if forwardedHost := lastHeaderListValue(call.req.Header, "X-Forwarded-Host"); forwardedHost != "" {
+		return forwardedHost
+	}
+
+	return call.req.Host
+}
+
+// isFromTrustedProxy checks whether the request was sent by a trusted proxy.
+func (call *Call) isFromTrustedProxy() bool {
+	return call.config.isTrustedProxy(proxy.StripPort(call.req.RemoteAddr))
+}
+
+// forwardedElements returns the parsed elements of all Forwarded headers in the request.
+func (call *Call) forwardedElements() []proxy.ForwardedElement {
+	forwarded := call.req.Header.Values("Forwarded")
+	if len(forwarded) == 0 {
+		return []proxy.ForwardedElement{}
+	}
+
+	return proxy.ParseForwarded(strings.Join(forwarded, ","))
+}
+
+// trustedForwardedElements returns the elements of the Forwarded headers added by trusted
+// proxies, ordered from the proxy closest to the client. The chain is walked from the
+// closest proxy like IP, ending at the element describing the first node which is not a
+// trusted proxy, as any elements before it may have been sent by the client.
+func (call *Call) trustedForwardedElements() []proxy.ForwardedElement {
+	forwarded := call.forwardedElements()
+
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		if !call.config.isTrustedProxy(forwarded[i].For) {
+			return forwarded[i:]
+		}
+	}
+
+	return forwarded
+}
+
+// forwardedFor returns the forwarding chain of nodes, preferring the Forwarded
+// header over the X-Forwarded-For header.
+func (call *Call) forwardedFor() []string {
+	nodes := []string{}
+
+	if forwarded := call.forwardedElements(); len(forwarded) > 0 {
+		for _, element := range forwarded {
+			if element.For != "" {
+				nodes = append(nodes, element.For)
+			}
+		}
+
+		return nodes
+	}
+
+	for _, value := range call.req.Header.Values("X-Forwarded-For") {
+		for _, node := range strings.Split(value, ",") {
+			if node = strings.TrimSpace(node); node != "" {
+				nodes = append(nodes, proxy.StripPort(node))
+			}
+		}
+	}
+
+	return nodes
+}
+
+// lastHeaderListValue returns the last value of a comma separated header list, spanning
+// all occurrences of the header. The last value is the one added by the closest proxy.
+func lastHeaderListValue(header http.Header, key string) string {
+	values := header.Values(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	last := values[len(values)-1]
+	if i := strings.LastIndex(last, ","); i >= 0 {
+		last = last[i+1:]
+	}
+
+	return strings.TrimSpace(last)
+}
+
+// isValidScheme checks whether given forwarded scheme is one the app can be served with.
+func isValidScheme(scheme string) bool {
+	return scheme == "http" || scheme == "https"
+}
@@ -11,7 +11,9 @@ type pathHandler struct {
 	PathFragment string
 	PathMatcher  routing.PathMatcher
 	Before       BeforeFunc
+	BeforeName   string
 	After        AfterFunc
+	AfterName    string
 	Head         HandlerFunc
 	Get          HandlerFunc
 	Post         HandlerFunc
@@ -19,6 +21,8 @@ type pathHandler struct {
 	Put          HandlerFunc
 	Delete       HandlerFunc
 	Options      HandlerFunc
+	Custom       map[string]HandlerFunc
+	RouteOptions map[string]routeOptions
 }
 
 func newPathHandlerFromPathFragment(pathFragment string) (pathHandler, error) {
@@ -39,6 +43,8 @@ func newPathHandlerFromPathFragment(pathFragment string) (pathHandler, error) {
 		Put:          nil,
 		Delete:       nil,
 		Options:      nil,
+		Custom:       map[string]HandlerFunc{},
+		RouteOptions: map[string]routeOptions{},
 	}, nil
 }
 
@@ -59,6 +65,6 @@ func (ph *pathHandler) GetHandlerByMethod(method string) HandlerFunc {
 	case http.MethodOptions:
 		return ph.Options
 	default:
-		return nil
+		return ph.Custom[method]
 	}
 }
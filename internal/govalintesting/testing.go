@@ -205,17 +205,21 @@ func (govalinHttp *GovalinHTTP) Raw() *httpclient.HttpClient {
 }
 
 func HTTPTestUtil(serverF TestFunc, testFunc ExecFunc) {
+	HTTPTestUtilWithConfig(func(config *govalin.Config) {}, serverF, testFunc)
+}
+
+// HTTPTestUtilWithConfig works like HTTPTestUtil, but creates the test server using given config function.
+func HTTPTestUtilWithConfig(configFunc govalin.ConfigFunc, serverF TestFunc, testFunc ExecFunc) {
 	port, err := freePort()
 	if err != nil {
 		log.Fatalf("Could not find free port. %v", err)
 	}
-	testInstance := govalin.New()
+	testInstance := govalin.New(configFunc)
 	server := serverF(testInstance)
 
 	go func() {
-		err = server.Start(port)
-		if err != nil {
-			log.Errorf("Failed to start test server. %v", err)
+		if startErr := server.Start(port); startErr != nil {
+			log.Errorf("Failed to start test server. %v", startErr)
 		}
 	}()
 
@@ -0,0 +1,127 @@
+package negotiation
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// MediaTypeMatches checks whether given media type matches given pattern. The pattern
+// may contain wildcards, such as "*/*" or "text/*". Parameters are ignored.
+func MediaTypeMatches(pattern string, mediaType string) bool {
+	patternType, patternSubtype := splitMediaType(pattern)
+	mediaTypeType, mediaTypeSubtype := splitMediaType(mediaType)
+
+	if patternType != "*" && patternType != mediaTypeType {
+		return false
+	}
+
+	return patternSubtype == "*" || patternSubtype == mediaTypeSubtype
+}
+
+// Accepts checks whether given Accept header accepts given media type. An empty
+// Accept header accepts any media type, while media ranges with a quality of 0
+// are considered explicitly not acceptable.
+func Accepts(acceptHeader string, mediaType string) bool {
+	return Quality(acceptHeader, mediaType) > 0
+}
+
+// Quality returns the quality given to given media type by given Accept header, as
+// given by the most specific media range matching the media type, e.g. letting
+// "application/json;q=0" exclude JSON from "*/*". An empty Accept header accepts any
+// media type with a quality of 1, while a media type not matching any media range
+// has a quality of 0.
+func Quality(acceptHeader string, mediaType string) float64 {
+	quality, _ := Preference(acceptHeader, mediaType)
+	return quality
+}
+
+// Preference returns the quality given to given media type by given Accept header,
+// along with the specificity of the media range it matched, from 0 for "*/*" to 2 for
+// the media type itself. Media types accepted with equal quality are preferred by the
+// specificity of their media ranges, e.g. "application/yaml" over "*/*".
+func Preference(acceptHeader string, mediaType string) (float64, int) {
+	if strings.TrimSpace(acceptHeader) == "" {
+		return 1, 0
+	}
+
+	quality := 0.0
+	bestSpecificity := -1
+
+	for _, mediaRange := range strings.Split(acceptHeader, ",") {
+		rangeType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil || !MediaTypeMatches(rangeType, mediaType) {
+			continue
+		}
+
+		if rangeSpecificity := specificity(rangeType); rangeSpecificity > bestSpecificity {
+			bestSpecificity = rangeSpecificity
+			quality = parseQuality(params["q"])
+		}
+	}
+
+	return quality, bestSpecificity
+}
+
+// specificity returns how specific given media range is, from 0 for "*/*" to 2 for a
+// full media type.
+func specificity(mediaRange string) int {
+	rangeType, rangeSubtype := splitMediaType(mediaRange)
+
+	switch {
+	case rangeType == "*":
+		return 0
+	case rangeSubtype == "*":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// parseQuality parses given quality value, defaulting to 1 when missing or invalid.
+func parseQuality(quality string) float64 {
+	parsedQuality, err := strconv.ParseFloat(strings.TrimSpace(quality), 64)
+	if err != nil || parsedQuality < 0 || parsedQuality > 1 {
+		return 1
+	}
+
+	return parsedQuality
+}
+
+// splitMediaType splits a media type into its lower cased type and subtype, ignoring parameters.
+func splitMediaType(mediaType string) (string, string) {
+	mediaType, _, _ = strings.Cut(mediaType, ";")
+	mediaTypeType, subtype, found := strings.Cut(strings.ToLower(strings.TrimSpace(mediaType)), "/")
+	if !found {
+		return mediaTypeType, ""
+	}
+
+	return mediaTypeType, subtype
+}
+
+// AcceptsEncoding checks whether given Accept-Encoding header accepts given content coding.
+// A coding listed explicitly takes precedence over the "*" wildcard, e.g. letting
+// "gzip;q=0, *" exclude gzip.
+func AcceptsEncoding(acceptEncodingHeader string, encoding string) bool {
+	quality := 0.0
+	matchedExplicitly := false
+
+	for _, coding := range strings.Split(acceptEncodingHeader, ",") {
+		name, params, _ := strings.Cut(coding, ";")
+		name = strings.TrimSpace(name)
+
+		explicit := strings.EqualFold(name, encoding)
+		if !explicit && (name != "*" || matchedExplicitly) {
+			continue
+		}
+
+		quality = 1
+		key, value, found := strings.Cut(strings.TrimSpace(params), "=")
+		if found && strings.TrimSpace(key) == "q" {
+			quality = parseQuality(value)
+		}
+		matchedExplicitly = explicit
+	}
+
+	return quality > 0
+}
@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"net"
+	"strings"
+)
+
+// ForwardedElement holds the parameters of a single element in a Forwarded header.
+type ForwardedElement struct {
+	For   string
+	By    string
+	Proto string
+	Host  string
+}
+
+// ParseForwarded parses a Forwarded header as described in RFC 7239. Each
+// comma separated element is returned in the order given in the header, with
+// quotes removed and any port and IPv6 brackets stripped from the for and by
+// parameters.
+func ParseForwarded(header string) []ForwardedElement {
+	elements := []ForwardedElement{}
+
+	for _, rawElement := range splitOutsideQuotes(header, ',') {
+		element := ForwardedElement{}
+
+		for _, pair := range splitOutsideQuotes(rawElement, ';') {
+			key, value, found := strings.Cut(pair, "=")
+			if !found {
+				continue
+			}
+
+			value = unquote(strings.TrimSpace(value))
+
+			switch strings.ToLower(strings.TrimSpace(key)) {
+			case "for":
+				element.For = StripPort(value)
+			case "by":
+				element.By = StripPort(value)
+			case "proto":
+				element.Proto = strings.ToLower(value)
+			case "host":
+				element.Host = value
+			}
+		}
+
+		elements = append(elements, element)
+	}
+
+	return elements
+}
+
+// StripPort removes any port and IPv6 brackets from given node, e.g.
+// "[2001:db8::1]:4711" becomes "2001:db8::1" and "192.0.2.43:47011" becomes "192.0.2.43".
+func StripPort(node string) string {
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(node, "["), "]")
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		return strings.ReplaceAll(value[1:len(value)-1], `\"`, `"`)
+	}
+
+	return value
+}
+
+// splitOutsideQuotes splits given string on given separator, ignoring separators within quotes.
+func splitOutsideQuotes(s string, separator rune) []string {
+	parts := []string{}
+	inQuotes := false
+	start := 0
+
+	for i, char := range s {
+		switch {
+		case char == '"':
+			inQuotes = !inQuotes
+		case char == separator && !inQuotes:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
@@ -1,6 +1,7 @@
 package routing
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 )
@@ -16,9 +17,15 @@ type PathMatcher struct {
 func NewPathMatcherFromString(path string) (PathMatcher, error) {
 	var pathSegments = []pathSegment{}
 
-	for _, pathPiece := range strings.Split(path, "/") {
+	pathPieces := strings.Split(path, "/")
+	for i, pathPiece := range pathPieces {
 		trimmedString := strings.Trim(pathPiece, " ")
 
+		// Only the leading and trailing slashes may produce empty segments
+		if trimmedString == "" && i != 0 && i != len(pathPieces)-1 {
+			return PathMatcher{}, fmt.Errorf("path '%s' contains an empty segment", path)
+		}
+
 		if trimmedString != "" {
 			pathSegment, err := newPathSegment(trimmedString)
 			if err != nil {
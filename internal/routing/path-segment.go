@@ -33,7 +33,10 @@ func newPathSegment(pathPiece string) (pathSegment, error) {
 
 	// Error in number of delimiters
 	if delimiterStartCount != delimiterEndCount {
-		return pathSegment{}, fmt.Errorf("number of '%d' and '%d' is not the same", delimiterStartCount, delimiterEndCount)
+		return pathSegment{}, fmt.Errorf(
+			"unbalanced braces in path segment '%s', found %d '%s' and %d '%s'",
+			pathPiece, delimiterStartCount, delimiterStart, delimiterEndCount, delimiterEnd,
+		)
 	}
 
 	// Wildcard
@@ -48,10 +51,17 @@ func newPathSegment(pathPiece string) (pathSegment, error) {
 
 	// Simple matcher
 	if totalDelimiters == 2 && pathPiece[0:1] == delimiterStart && pathPiece[len(pathPiece)-1:] == delimiterEnd {
+		if len(pathPiece) == len(delimiterStart)+len(delimiterEnd) {
+			return pathSegment{}, fmt.Errorf("missing param name in path segment '%s'", pathPiece)
+		}
+
 		return createParameterPathSegment(pathPiece), nil
 	}
 
-	return pathSegment{}, nil
+	return pathSegment{}, fmt.Errorf(
+		"unsupported path segment '%s', a segment must be a literal, a single '%s' or a {param} filling the segment",
+		pathPiece, wildcard,
+	)
 }
 
 func createNormalPathSegment(pathPiece string) pathSegment {
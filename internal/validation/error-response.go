@@ -12,7 +12,9 @@ var defaultErrorMessages = map[int]string{
 	403: "Forbidden",
 	404: "Not found",
 	405: "Method not allowed",
+	406: "Not acceptable",
 	409: "Conflict",
+	415: "Unsupported media type",
 	500: "Server error",
 	501: "Not implemented",
 	502: "Bad gateway",
@@ -0,0 +1,83 @@
+package govalin
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// JSONArrayWriter streams the elements of a JSON array to the response.
+type JSONArrayWriter struct {
+	call     *Call
+	elements int
+	closed   bool
+	err      error
+}
+
+// Stream a JSON array to the response
+//
+// JSONArray sets the content-type of the response as application/json, writes the
+// opening bracket of a JSON array and returns a writer for appending elements one
+// at a time. This allows sending huge collections without holding them in memory.
+// The writer must be closed to terminate the array, preferably using defer:
+//
+//	array := call.JSONArray()
+//	defer array.Close()
+func (call *Call) JSONArray() *JSONArrayWriter {
+	call.w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	call.w.Header().Set("Accept-Ranges", "none")
+	call.sendStatusOrDefault()
+
+	arrayWriter := &JSONArrayWriter{call: call}
+	arrayWriter.write([]byte("["))
+
+	return arrayWriter
+}
+
+// Append serializes given object as JSON and writes it as the next element of the array.
+func (arrayWriter *JSONArrayWriter) Append(obj any) error {
+	if arrayWriter.closed {
+		return fmt.Errorf("cannot append to closed JSON array")
+	}
+
+	jsonBytes, err := arrayWriter.call.marshalJSON(obj)
+	if err != nil {
+		return fmt.Errorf("failed to JSON marshal array element. %w", err)
+	}
+
+	if arrayWriter.elements > 0 {
+		arrayWriter.write([]byte(","))
+	}
+	arrayWriter.write(jsonBytes)
+	arrayWriter.elements++
+
+	return arrayWriter.err
+}
+
+// Close terminates the array by writing the closing bracket. Closing an already
+// closed writer does nothing.
+func (arrayWriter *JSONArrayWriter) Close() error {
+	if arrayWriter.closed {
+		return nil
+	}
+	arrayWriter.closed = true
+
+	arrayWriter.write([]byte("]"))
+
+	return arrayWriter.err
+}
+
+// write writes and flushes given bytes, keeping the first error that occurs.
+func (arrayWriter *JSONArrayWriter) write(bytes []byte) {
+	if arrayWriter.err != nil {
+		return
+	}
+
+	if _, err := arrayWriter.call.w.Write(bytes); err != nil {
+		arrayWriter.err = fmt.Errorf("failed to write JSON array to response. %w", err)
+		return
+	}
+
+	if flusher, ok := arrayWriter.call.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,70 @@
+package govalin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// jsonLimitError is returned when a JSON body exceeds the configured depth or element limits.
+type jsonLimitError struct {
+	reason string
+}
+
+func (err *jsonLimitError) Error() string {
+	return err.reason
+}
+
+// jsonContainer keeps track of the tokens seen in an open JSON object or array.
+type jsonContainer struct {
+	isObject bool
+	tokens   int
+}
+
+// checkJSONLimits walks the tokens of given JSON data, verifying that the nesting depth and the
+// number of elements in each object or array does not exceed the given limits. A limit of 0
+// disables the check. Syntax errors are left to be reported when unmarshalling.
+func checkJSONLimits(data []byte, maxDepth int, maxElements int) error {
+	if maxDepth <= 0 && maxElements <= 0 {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	containers := []jsonContainer{}
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil
+		}
+
+		delim, isDelim := token.(json.Delim)
+		if isDelim && (delim == '}' || delim == ']') {
+			containers = containers[:len(containers)-1]
+			continue
+		}
+
+		if len(containers) > 0 {
+			parent := &containers[len(containers)-1]
+			parent.tokens++
+
+			// Objects alternate between keys and values, so only keys are counted as elements
+			elements := parent.tokens
+			if parent.isObject {
+				elements = (parent.tokens + 1) / 2
+			}
+
+			if maxElements > 0 && elements > maxElements {
+				return &jsonLimitError{reason: fmt.Sprintf("JSON body exceeds max number of elements of %d", maxElements)}
+			}
+		}
+
+		if isDelim {
+			containers = append(containers, jsonContainer{isObject: delim == '{'})
+
+			if maxDepth > 0 && len(containers) > maxDepth {
+				return &jsonLimitError{reason: fmt.Sprintf("JSON body exceeds max depth of %d", maxDepth)}
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package govalin
+
+import "net/http"
+
+// MultiStatusItem is the result of a single item in a multi-status response.
+type MultiStatusItem struct {
+	ID     string `json:"id"`
+	Status int    `json:"status"`
+	Body   any    `json:"body,omitempty"`
+}
+
+// MultiStatusBuilder builds a 207 Multi-Status response for batch operations.
+type MultiStatusBuilder struct {
+	call  *Call
+	items []MultiStatusItem
+}
+
+// Build a multi-status response
+//
+// MultiStatus returns a builder for reporting the results of batch operations where each
+// item can succeed or fail individually. Sending the response writes a 207 Multi-Status
+// with a JSON array of per-item results, each on the form:
+//
+//	{"id": "<item id>", "status": <item status>, "body": <optional item body>}
+func (call *Call) MultiStatus() *MultiStatusBuilder {
+	return &MultiStatusBuilder{call: call, items: []MultiStatusItem{}}
+}
+
+// Add the result of an item with given ID, status and optional body.
+func (builder *MultiStatusBuilder) Add(id string, status int, body any) *MultiStatusBuilder {
+	builder.items = append(builder.items, MultiStatusItem{ID: id, Status: status, Body: body})
+	return builder
+}
+
+// Send the multi-status response.
+func (builder *MultiStatusBuilder) Send() {
+	builder.call.Status(http.StatusMultiStatus)
+	builder.call.JSON(builder.items)
+}
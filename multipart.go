@@ -0,0 +1,76 @@
+package govalin
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+)
+
+// MultipartWriter streams the parts of a multipart/mixed response.
+type MultipartWriter struct {
+	call   *Call
+	writer *multipart.Writer
+	closed bool
+}
+
+// Stream a multipart response
+//
+// Multipart sets the content-type of the response as multipart/mixed with a random
+// boundary and returns a writer for streaming the parts of the response, such as
+// metadata followed by binary content, without buffering the full response. The
+// writer must be closed to terminate the response, preferably using defer:
+//
+//	parts := call.Multipart()
+//	defer parts.Close()
+func (call *Call) Multipart() *MultipartWriter {
+	writer := multipart.NewWriter(call.w)
+
+	call.w.Header().Set("Content-Type", "multipart/mixed; boundary="+writer.Boundary())
+	call.w.Header().Set("Accept-Ranges", "none")
+	call.sendStatusOrDefault()
+
+	return &MultipartWriter{call: call, writer: writer}
+}
+
+// NextPart starts a new part with given headers, returning a writer for the body of
+// the part. The previous part is flushed to the client, and its writer must not be
+// used anymore.
+func (multipartWriter *MultipartWriter) NextPart(header http.Header) (io.Writer, error) {
+	if multipartWriter.closed {
+		return nil, fmt.Errorf("cannot add part to closed multipart response")
+	}
+
+	multipartWriter.flush()
+
+	part, err := multipartWriter.writer.CreatePart(textproto.MIMEHeader(header))
+	if err != nil {
+		return nil, fmt.Errorf("failed to write multipart response part. %w", err)
+	}
+
+	return part, nil
+}
+
+// Close terminates the response by writing the closing boundary. Closing an already
+// closed writer does nothing.
+func (multipartWriter *MultipartWriter) Close() error {
+	if multipartWriter.closed {
+		return nil
+	}
+	multipartWriter.closed = true
+
+	if err := multipartWriter.writer.Close(); err != nil {
+		return fmt.Errorf("failed to terminate multipart response. %w", err)
+	}
+
+	multipartWriter.flush()
+
+	return nil
+}
+
+func (multipartWriter *MultipartWriter) flush() {
+	if flusher, ok := multipartWriter.call.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
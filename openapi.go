@@ -0,0 +1,111 @@
+package govalin
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type openAPISpec struct {
+	OpenAPI string                          `yaml:"openapi"`
+	Paths   map[string]map[string]yaml.Node `yaml:"paths"`
+}
+
+type openAPIOperation struct {
+	OperationID string `yaml:"operationId"`
+}
+
+// Operation keys of an OpenAPI path item mapped to their HTTP methods.
+var openAPIMethods = map[string]string{
+	"get":     http.MethodGet,
+	"post":    http.MethodPost,
+	"put":     http.MethodPut,
+	"patch":   http.MethodPatch,
+	"delete":  http.MethodDelete,
+	"options": http.MethodOptions,
+	"head":    http.MethodHead,
+}
+
+// Register routes from an OpenAPI spec
+//
+// FromOpenAPI parses an OpenAPI 3 document in JSON or YAML and registers a route for
+// each operation declared in its paths, using the handler with the same key as the
+// operationId of the operation. Path templates such as /users/{id} are used as is, so
+// path params are available through Call.PathParam. Returns an error if the spec can't
+// be parsed, or if an operation is missing an operationId or a handler, so an outdated
+// implementation is caught at startup. Request and response validation based on the
+// schemas of the spec is not supported.
+func (server *App) FromOpenAPI(spec []byte, handlers map[string]HandlerFunc) error {
+	var openAPI openAPISpec
+	if err := yaml.Unmarshal(spec, &openAPI); err != nil {
+		return fmt.Errorf("failed to parse OpenAPI spec. %w", err)
+	}
+
+	if !strings.HasPrefix(openAPI.OpenAPI, "3.") {
+		return fmt.Errorf("unsupported OpenAPI version '%s', expected 3.x", openAPI.OpenAPI)
+	}
+
+	routes := []openAPIRoute{}
+	problems := []string{}
+	usedHandlers := map[string]bool{}
+
+	for path, pathItem := range openAPI.Paths {
+		// Path items contain other fields than operations, such as parameters, so only operations are decoded
+		for key, node := range pathItem {
+			method, isOperation := openAPIMethods[strings.ToLower(key)]
+			if !isOperation {
+				continue
+			}
+
+			var operation openAPIOperation
+			if err := node.Decode(&operation); err != nil {
+				return fmt.Errorf("failed to parse OpenAPI operation %s %s. %w", method, path, err)
+			}
+
+			handler, found := handlers[operation.OperationID]
+			switch {
+			case operation.OperationID == "":
+				problems = append(problems, fmt.Sprintf("%s %s is missing an operationId", method, path))
+			case !found:
+				problems = append(problems, fmt.Sprintf("missing handler for operation '%s'", operation.OperationID))
+			default:
+				usedHandlers[operation.OperationID] = true
+				routes = append(routes, openAPIRoute{method: method, path: path, handler: handler})
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("invalid OpenAPI routes: %s", strings.Join(problems, ", "))
+	}
+
+	for operationID := range handlers {
+		if !usedHandlers[operationID] {
+			log.Warnf("Handler for operation '%s' is not declared in the OpenAPI spec", operationID)
+		}
+	}
+
+	// Register routes in a stable order, as route matching depends on the order of registration
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].path != routes[j].path {
+			return routes[i].path < routes[j].path
+		}
+		return routes[i].method < routes[j].method
+	})
+
+	for _, route := range routes {
+		server.addMethod(route.method, server.fullPath(route.path), route.handler, nil)
+	}
+
+	return nil
+}
+
+type openAPIRoute struct {
+	method  string
+	path    string
+	handler HandlerFunc
+}
@@ -0,0 +1,73 @@
+package govalin
+
+import (
+	"expvar"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/pprof"
+	runtimepprof "runtime/pprof"
+	"strings"
+)
+
+// Enable profiling endpoints
+//
+// EnableProfiling mounts the net/http/pprof handlers (index, cmdline, profile, symbol,
+// trace and named profiles such as heap and goroutine) and the expvar handler (vars)
+// under given prefix. The endpoints expose sensitive information about the running
+// process and allow for expensive profiling, so they are protected by given guard
+// function. Requests for which the guard returns false are answered with a 403
+// Forbidden. Profiling is never enabled unless this function is called.
+func (server *App) EnableProfiling(prefix string, guard func(call *Call) bool) *App {
+	guarded := func(handler http.Handler) HandlerFunc {
+		return func(call *Call) {
+			if !guard(call) {
+				call.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+
+			handler.ServeHTTP(call.w, call.req)
+			call.statusWritten = true
+		}
+	}
+
+	server.Get(prefix, guarded(profilingIndexHandler(server.fullPath(prefix))))
+	server.Get(prefix+"/cmdline", guarded(http.HandlerFunc(pprof.Cmdline)))
+	server.Get(prefix+"/profile", guarded(http.HandlerFunc(pprof.Profile)))
+	server.Get(prefix+"/symbol", guarded(http.HandlerFunc(pprof.Symbol)))
+	server.Post(prefix+"/symbol", guarded(http.HandlerFunc(pprof.Symbol)))
+	server.Get(prefix+"/trace", guarded(http.HandlerFunc(pprof.Trace)))
+	server.Get(prefix+"/vars", guarded(expvar.Handler()))
+	server.Get(prefix+"/{profile}", func(call *Call) {
+		guarded(pprof.Handler(call.PathParam("profile")))(call)
+	})
+
+	return server
+}
+
+// profilingIndexHandler lists the profiling endpoints mounted under given full prefix.
+// The index of net/http/pprof uses links relative to /debug/pprof/, so a custom index
+// with absolute links is used instead.
+func profilingIndexHandler(fullPrefix string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var index strings.Builder
+		index.WriteString("<html><head><title>Profiling</title></head><body><ul>")
+
+		for _, endpoint := range []string{"cmdline", "profile", "symbol", "trace", "vars"} {
+			fmt.Fprintf(&index, `<li><a href="%s/%s">%s</a></li>`, fullPrefix, endpoint, endpoint)
+		}
+
+		for _, profile := range runtimepprof.Profiles() {
+			name := html.EscapeString(profile.Name())
+			fmt.Fprintf(&index, `<li><a href="%s/%s?debug=1">%s</a> (%d)</li>`, fullPrefix, name, name, profile.Count())
+		}
+
+		index.WriteString("</ul></body></html>")
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, err := w.Write([]byte(index.String()))
+		if err != nil {
+			log.Errorf("Error when trying write to response, %v", err)
+		}
+	})
+}
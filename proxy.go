@@ -0,0 +1,179 @@
+package govalin
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ProxyOption configures how a request is proxied by Call.Proxy.
+type ProxyOption func(options *proxyOptions)
+
+type proxyOptions struct {
+	rewriteBody bool
+}
+
+// Rewrite bodies of proxied responses
+//
+// RewriteBody makes Call.Proxy run the body of the upstream response through the
+// configured response filters, e.g. for injecting content into proxied HTML. Bodies
+// compressed using gzip are transparently decompressed for the filters and compressed
+// again before being sent to the client. Brotli and other content codings aren't
+// supported, so they are removed from the Accept-Encoding header sent upstream, and
+// bodies an upstream still sends using them are passed through untouched. Responses
+// without a body, such as responses to HEAD requests, 204 No Content and 304 Not
+// Modified, are passed through untouched as well.
+func RewriteBody() ProxyOption {
+	return func(options *proxyOptions) {
+		options.rewriteBody = true
+	}
+}
+
+// Proxy the request to an upstream server
+//
+// Proxy forwards the request to given upstream URL, joining the path of the upstream
+// URL with the path of the request, and writes the upstream response to the response.
+// The response filters are bypassed unless the RewriteBody option is given. Returns a
+// bad gateway error, which is handled as a 502 by Call.Error, if the upstream can't
+// be reached.
+func (call *Call) Proxy(upstream string, options ...ProxyOption) error {
+	upstreamURL, err := url.Parse(upstream)
+	if err != nil {
+		return newErrorFromType(serverError, fmt.Errorf("invalid upstream URL '%s'. %w", upstream, err))
+	}
+
+	proxyOptions := proxyOptions{}
+	for _, option := range options {
+		option(&proxyOptions)
+	}
+
+	var proxyErr error
+
+	reverseProxy := httputil.NewSingleHostReverseProxy(upstreamURL)
+	director := reverseProxy.Director
+	reverseProxy.Director = func(req *http.Request) {
+		director(req)
+
+		// The body has already been consumed by the handler, forward the cached body
+		if call.bodyBytes != nil {
+			req.Body = io.NopCloser(bytes.NewReader(call.bodyBytes))
+			req.ContentLength = int64(len(call.bodyBytes))
+		}
+
+		if proxyOptions.rewriteBody {
+			req.Header.Set("Accept-Encoding", rewritableAcceptEncoding(req.Header.Values("Accept-Encoding")))
+		}
+	}
+	reverseProxy.ErrorHandler = func(_ http.ResponseWriter, _ *http.Request, err error) {
+		proxyErr = err
+	}
+	if proxyOptions.rewriteBody {
+		reverseProxy.ModifyResponse = call.rewriteProxiedBody
+	}
+
+	reverseProxy.ServeHTTP(call.w, call.req)
+
+	if proxyErr != nil {
+		return newErrorFromType(badGatewayError, fmt.Errorf("failed to proxy request to upstream. %w", proxyErr))
+	}
+
+	call.statusWritten = true
+
+	return nil
+}
+
+// rewriteProxiedBody runs the body of given upstream response through the response
+// filters, decoding and encoding gzip compressed bodies.
+func (call *Call) rewriteProxiedBody(resp *http.Response) error {
+	if !hasBody(resp) {
+		return nil
+	}
+
+	contentEncoding := resp.Header.Get("Content-Encoding")
+
+	switch contentEncoding {
+	case "", "identity", "gzip":
+	default:
+		return nil
+	}
+	defer resp.Body.Close()
+
+	var reader io.Reader = resp.Body
+	if contentEncoding == "gzip" {
+		gzipReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to decompress upstream response. %w", err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read upstream response. %w", err)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	for _, responseFilter := range call.config.responseFilters {
+		body = responseFilter(call, contentType, body)
+	}
+
+	if contentEncoding == "gzip" {
+		var compressed bytes.Buffer
+		gzipWriter := gzip.NewWriter(&compressed)
+		if _, err = gzipWriter.Write(body); err != nil {
+			return fmt.Errorf("failed to compress upstream response. %w", err)
+		}
+		if err = gzipWriter.Close(); err != nil {
+			return fmt.Errorf("failed to compress upstream response. %w", err)
+		}
+		body = compressed.Bytes()
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+
+	return nil
+}
+
+// hasBody checks whether given upstream response has a body which can be rewritten.
+func hasBody(resp *http.Response) bool {
+	if resp.Request != nil && resp.Request.Method == http.MethodHead {
+		return false
+	}
+
+	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return false
+	}
+
+	return resp.ContentLength != 0
+}
+
+// rewritableAcceptEncoding returns given Accept-Encoding header values limited to the
+// content codings rewriteProxiedBody can rewrite, falling back to identity when none
+// of them are accepted.
+func rewritableAcceptEncoding(acceptEncoding []string) string {
+	codings := []string{}
+
+	for _, coding := range strings.Split(strings.Join(acceptEncoding, ","), ",") {
+		name, _, _ := strings.Cut(coding, ";")
+		name = strings.TrimSpace(name)
+
+		if strings.EqualFold(name, "gzip") || strings.EqualFold(name, "identity") {
+			codings = append(codings, strings.TrimSpace(coding))
+		}
+	}
+
+	if len(codings) == 0 {
+		return "identity"
+	}
+
+	return strings.Join(codings, ", ")
+}
@@ -0,0 +1,81 @@
+package govalin
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkkummermo/govalin/internal/validation"
+)
+
+// Redirect to given location
+//
+// Redirect sets the Location header and sends a 302 Found, or the given status code.
+// Never redirect to a location taken from user input using Redirect, use SafeRedirect.
+func (call *Call) Redirect(location string, statusCode ...int) {
+	status := http.StatusFound
+	if len(statusCode) > 0 {
+		status = statusCode[0]
+	}
+
+	call.w.Header().Set("Location", location)
+	call.Status(status)
+	call.sendStatusOrDefault()
+}
+
+// Redirect to given location if it's safe
+//
+// SafeRedirect protects against open redirects, where an attacker crafts a link to your
+// site which redirects the user to a malicious site, e.g. through the return URL of a
+// login flow. The location must either be a relative path on the same origin, or an
+// absolute http(s) URL on one of the allowed hosts. Protocol-relative locations such as
+// "//evil.com", non-http schemes such as "javascript:" and downgrades from https to
+// http are rejected. If the location is rejected, a validation error is returned and no
+// redirect is made.
+func (call *Call) SafeRedirect(location string, allowedHosts []string) error {
+	if !call.isSafeRedirect(location, allowedHosts) {
+		return validation.NewError(
+			validation.NewErrorResponse(
+				http.StatusBadRequest,
+				validation.NewParameterErrorDetail("redirect", fmt.Sprintf("Unsafe redirect target '%s'", location)),
+			),
+		)
+	}
+
+	call.Redirect(location)
+
+	return nil
+}
+
+func (call *Call) isSafeRedirect(location string, allowedHosts []string) bool {
+	// Browsers treat backslashes as slashes, making "/\evil.com" protocol-relative
+	if location == "" || strings.ContainsAny(location, "\\\r\n\t") {
+		return false
+	}
+
+	target, err := url.Parse(location)
+	if err != nil {
+		return false
+	}
+
+	if target.Scheme == "" && target.Host == "" {
+		return strings.HasPrefix(location, "/") && !strings.HasPrefix(location, "//")
+	}
+
+	if target.Scheme != "https" && target.Scheme != "http" {
+		return false
+	}
+
+	if target.Scheme == "http" && call.Scheme() == "https" {
+		return false
+	}
+
+	for _, allowedHost := range allowedHosts {
+		if strings.EqualFold(target.Host, allowedHost) || strings.EqualFold(target.Hostname(), allowedHost) {
+			return true
+		}
+	}
+
+	return false
+}
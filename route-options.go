@@ -0,0 +1,101 @@
+package govalin
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/pkkummermo/govalin/internal/negotiation"
+)
+
+// RouteOption configures an endpoint handler when registering it.
+type RouteOption func(options *routeOptions)
+
+type routeOptions struct {
+	produces []string
+	consumes []string
+}
+
+func newRouteOptions(options []RouteOption) routeOptions {
+	newOptions := routeOptions{
+		produces: []string{},
+		consumes: []string{},
+	}
+
+	for _, option := range options {
+		option(&newOptions)
+	}
+
+	return newOptions
+}
+
+// Declare the content types a route produces
+//
+// Produces declares the content types the endpoint handler can respond with. If
+// the Accept header of a request accepts none of them, the request is answered
+// with a 406 Not Acceptable without running the endpoint handler.
+func Produces(contentTypes ...string) RouteOption {
+	return func(options *routeOptions) {
+		options.produces = append(options.produces, contentTypes...)
+	}
+}
+
+// Declare the content types a route consumes
+//
+// Consumes declares the content types the endpoint handler accepts as request
+// body. Content types may contain wildcards such as "text/*". If a request has
+// a body of any other content type, the request is answered with a 415 Unsupported
+// Media Type without running the endpoint handler.
+func Consumes(contentTypes ...string) RouteOption {
+	return func(options *routeOptions) {
+		options.consumes = append(options.consumes, contentTypes...)
+	}
+}
+
+// negotiate verifies that the request is compatible with the declared content types of the route.
+func (options routeOptions) negotiate(call *Call) error {
+	if len(options.consumes) > 0 {
+		if err := options.checkConsumes(call); err != nil {
+			return err
+		}
+	}
+
+	if len(options.produces) > 0 {
+		accept := strings.Join(call.req.Header.Values("Accept"), ",")
+		for _, contentType := range options.produces {
+			if negotiation.Accepts(accept, contentType) {
+				return nil
+			}
+		}
+
+		return newErrorFromType(
+			notAcceptableError,
+			fmt.Errorf("accepted content types are %s", strings.Join(options.produces, ", ")),
+		)
+	}
+
+	return nil
+}
+
+func (options routeOptions) checkConsumes(call *Call) error {
+	contentType := call.Header("Content-Type")
+
+	// Requests without a body have nothing to consume
+	if contentType == "" && call.req.ContentLength == 0 {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		for _, consumed := range options.consumes {
+			if negotiation.MediaTypeMatches(consumed, mediaType) {
+				return nil
+			}
+		}
+	}
+
+	return newErrorFromType(
+		unsupportedMediaTypeError,
+		fmt.Errorf("supported content types are %s", strings.Join(options.consumes, ", ")),
+	)
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkkummermo/govalin/internal/validation"
@@ -19,25 +21,68 @@ const (
 	maxBodyReadSize int64 = 4096
 	// Max time for shutdown.
 	shutdownTimeoutInMS = 200
+	// Seconds clients are asked to wait before retrying requests rejected due to load.
+	loadSheddingRetryAfterInSeconds = 1
 )
 
 type HandlerFunc func(call *Call)
 type BeforeFunc func(call *Call) bool
 type AfterFunc func(call *Call)
+type ErrorHandlerFunc func(call *Call, err error)
+
+// MiddlewareStage is the stage of the request pipeline a middleware runs in.
+type MiddlewareStage string
+
+const (
+	MiddlewareStageBefore MiddlewareStage = "before"
+	MiddlewareStageAfter  MiddlewareStage = "after"
+)
+
+// MiddlewareInfo describes a registered middleware.
+type MiddlewareInfo struct {
+	Stage MiddlewareStage
+	Path  string
+	Name  string
+}
 
 type App struct {
-	createdTime     time.Time
-	started         bool
-	port            uint16
-	mux             *http.ServeMux
-	server          http.Server
-	currentFragment string
-	pathHandlers    []pathHandler
+	config             *Config
+	createdTime        time.Time
+	started            bool
+	port               uint16
+	mux                *http.ServeMux
+	server             http.Server
+	currentFragment    string
+	pathHandlers       []pathHandler
+	spaHandlers        []spaHandler
+	errorHandlers      []errorHandler
+	requestSlots       chan struct{}
+	registrationErrors []error
 }
 
 // New creates a new Govalin App instance.
-func New() *App {
-	return &App{createdTime: time.Now(), port: defaultPort, currentFragment: "", mux: http.NewServeMux()}
+//
+// The app can optionally be configured by passing one or more config functions.
+func New(configFuncs ...ConfigFunc) *App {
+	config := newDefaultConfig()
+	for _, configFunc := range configFuncs {
+		configFunc(config)
+	}
+
+	var requestSlots chan struct{}
+	if config.maxRequests > 0 {
+		requestSlots = make(chan struct{}, config.maxRequests)
+	}
+
+	return &App{
+		config:          config,
+		createdTime:     time.Now(),
+		port:            defaultPort,
+		currentFragment: "",
+		mux:             http.NewServeMux(),
+		errorHandlers:   []errorHandler{},
+		requestSlots:    requestSlots,
+	}
 }
 
 // Add a route to the given path
@@ -46,162 +91,258 @@ func New() *App {
 // methods or even more routes into. This allows for hierarchical building
 // of routes and methods.
 func (server *App) Route(path string, scopeFunc func()) *App {
-	server.currentFragment += path
+	parentFragment := server.currentFragment
+	server.currentFragment = server.fullPath(path)
 
 	scopeFunc()
 
-	server.currentFragment = server.currentFragment[:len(server.currentFragment)-1]
+	server.currentFragment = parentFragment
 
 	return server
 }
 
-func (server *App) addMethod(method string, fullPath string, methodHandler HandlerFunc) {
-	var handler = server.getOrCreatePathHandlerByPath(fullPath)
+// fullPath joins given path onto the fragment of the current route. A slash ending the
+// fragment and a slash starting the path are collapsed, e.g. when joining Route("/api/")
+// and Get("/users"), while empty segments written within the path itself are kept.
+func (server *App) fullPath(path string) string {
+	if strings.HasSuffix(server.currentFragment, "/") && strings.HasPrefix(path, "/") {
+		return server.currentFragment + path[1:]
+	}
+
+	return server.currentFragment + path
+}
+
+func (server *App) addMethod(method string, fullPath string, methodHandler HandlerFunc, options []RouteOption) {
+	if !isValidMethod(method) {
+		server.addRegistrationError(fmt.Errorf("invalid method '%s' on path %s", method, fullPath))
+		return
+	}
+
+	handler, ok := server.getOrCreatePathHandlerByPath(fullPath)
+	if !ok {
+		return
+	}
+
+	if handler.GetHandlerByMethod(method) != nil {
+		server.addRegistrationError(fmt.Errorf("%s already exists on path %s", method, fullPath))
+		return
+	}
 
 	switch method {
 	case http.MethodGet:
-		if handler.Get != nil {
-			log.Fatalf("GET already exists on path %s.", fullPath)
-		}
 		handler.Get = methodHandler
 	case http.MethodPost:
-		if handler.Post != nil {
-			log.Fatalf("POST already exists on path %s.", fullPath)
-		}
 		handler.Post = methodHandler
 	case http.MethodPut:
-		if handler.Put != nil {
-			log.Fatalf("PUT already exists on path %s.", fullPath)
-		}
 		handler.Put = methodHandler
 	case http.MethodPatch:
-		if handler.Patch != nil {
-			log.Fatalf("PATCH already exists on path %s.", fullPath)
-		}
 		handler.Patch = methodHandler
 	case http.MethodDelete:
-		if handler.Delete != nil {
-			log.Fatalf("DELETE already exists on path %s.", fullPath)
-		}
 		handler.Delete = methodHandler
 	case http.MethodOptions:
-		if handler.Options != nil {
-			log.Fatalf("OPTIONS already exists on path %s.", fullPath)
-		}
 		handler.Options = methodHandler
 	case http.MethodHead:
-		if handler.Head != nil {
-			log.Fatalf("HEAD already exists on path %s.", fullPath)
-		}
 		handler.Head = methodHandler
 	default:
-		log.Warnf("Unhandled method %s on path %s", method, fullPath)
-		return
+		handler.Custom[method] = methodHandler
 	}
+
+	handler.RouteOptions[method] = newRouteOptions(options)
 }
 
 // Add a before handler to given path
 //
 // Add a before handler that will run before any endpoint handler which matches
 // the same request. If the before handler returns false, the request will be
-// short circuited.
-func (server *App) Before(path string, beforeFunc BeforeFunc) {
-	fullPath := server.currentFragment + path
-	var handler = server.getOrCreatePathHandlerByPath(fullPath)
+// short circuited. The handler can optionally be given a name, which is listed
+// by Middleware.
+func (server *App) Before(path string, beforeFunc BeforeFunc, name ...string) {
+	fullPath := server.fullPath(path)
+	handler, ok := server.getOrCreatePathHandlerByPath(fullPath)
+	if !ok {
+		return
+	}
 
 	if handler.Before != nil {
-		log.Fatalf("Before already exists on path %s.", fullPath)
+		server.addRegistrationError(fmt.Errorf("before handler already exists on path %s", fullPath))
+		return
 	}
 
 	handler.Before = beforeFunc
+	if len(name) > 0 {
+		handler.BeforeName = name[0]
+	}
 }
 
 // Add an after handler to given path
 //
 // Add an after handler that will run after any endpoint handler which matches
-// the same request.
-func (server *App) After(path string, afterFunc AfterFunc) {
-	fullPath := server.currentFragment + path
-	var handler = server.getOrCreatePathHandlerByPath(fullPath)
+// the same request. The handler can optionally be given a name, which is listed
+// by Middleware.
+func (server *App) After(path string, afterFunc AfterFunc, name ...string) {
+	fullPath := server.fullPath(path)
+	handler, ok := server.getOrCreatePathHandlerByPath(fullPath)
+	if !ok {
+		return
+	}
 
 	if handler.After != nil {
-		log.Fatalf("Before already exists on path %s.", fullPath)
+		server.addRegistrationError(fmt.Errorf("after handler already exists on path %s", fullPath))
+		return
 	}
 
 	handler.After = afterFunc
+	if len(name) > 0 {
+		handler.AfterName = name[0]
+	}
+}
+
+// List the registered middleware
+//
+// Middleware returns the registered before and after handlers in the order they
+// run for a request matching all of them: before handlers first, followed by after
+// handlers, each ordered by when their path was first registered with the app.
+// Useful for diagnosing ordering issues in large apps.
+func (server *App) Middleware() []MiddlewareInfo {
+	befores := []MiddlewareInfo{}
+	afters := []MiddlewareInfo{}
+
+	for _, pathHandler := range server.pathHandlers {
+		if pathHandler.Before != nil {
+			befores = append(befores, MiddlewareInfo{
+				Stage: MiddlewareStageBefore,
+				Path:  pathHandler.PathFragment,
+				Name:  pathHandler.BeforeName,
+			})
+		}
+
+		if pathHandler.After != nil {
+			afters = append(afters, MiddlewareInfo{
+				Stage: MiddlewareStageAfter,
+				Path:  pathHandler.PathFragment,
+				Name:  pathHandler.AfterName,
+			})
+		}
+	}
+
+	return append(befores, afters...)
 }
 
 // Add a GET handler
 //
 // Add a GET handler based on where you are in a hierarchy composed from
-// other method handlers or route handlers.
-func (server *App) Get(path string, handler HandlerFunc) *App {
-	server.addMethod(http.MethodGet, server.currentFragment+path, handler)
+// other method handlers or route handlers, optionally configured by route options.
+func (server *App) Get(path string, handler HandlerFunc, options ...RouteOption) *App {
+	server.addMethod(http.MethodGet, server.fullPath(path), handler, options)
 	return server
 }
 
 // Add a POST handler
 //
 // Add a POST handler based on where you are in a hierarchy composed from
-// other method handlers or route handlers.
-func (server *App) Post(path string, handler HandlerFunc) *App {
-	server.addMethod(http.MethodPost, server.currentFragment+path, handler)
+// other method handlers or route handlers, optionally configured by route options.
+func (server *App) Post(path string, handler HandlerFunc, options ...RouteOption) *App {
+	server.addMethod(http.MethodPost, server.fullPath(path), handler, options)
 	return server
 }
 
 // Add a PUT handler
 //
 // Add a PUT handler based on where you are in a hierarchy composed from
-// other method handlers or route handlers.
-func (server *App) Put(path string, handler HandlerFunc) *App {
-	server.addMethod(http.MethodPut, server.currentFragment+path, handler)
+// other method handlers or route handlers, optionally configured by route options.
+func (server *App) Put(path string, handler HandlerFunc, options ...RouteOption) *App {
+	server.addMethod(http.MethodPut, server.fullPath(path), handler, options)
 	return server
 }
 
 // Add a PATCH handler
 //
 // Add a PATCH handler based on where you are in a hierarchy composed from
-// other method handlers or route handlers.
-func (server *App) Patch(path string, handler HandlerFunc) *App {
-	server.addMethod(http.MethodPatch, server.currentFragment+path, handler)
+// other method handlers or route handlers, optionally configured by route options.
+func (server *App) Patch(path string, handler HandlerFunc, options ...RouteOption) *App {
+	server.addMethod(http.MethodPatch, server.fullPath(path), handler, options)
 	return server
 }
 
 // Add a DELETE handler
 //
 // Add a DELETE handler based on where you are in a hierarchy composed from
-// other method handlers or route handlers.
-func (server *App) Delete(path string, handler HandlerFunc) *App {
-	server.addMethod(http.MethodDelete, server.currentFragment+path, handler)
+// other method handlers or route handlers, optionally configured by route options.
+func (server *App) Delete(path string, handler HandlerFunc, options ...RouteOption) *App {
+	server.addMethod(http.MethodDelete, server.fullPath(path), handler, options)
 	return server
 }
 
 // Add a OPTIONS handler
 //
 // Add a OPTIONS handler based on where you are in a hierarchy composed from
-// other method handlers or route handlers.
-func (server *App) Options(path string, handler HandlerFunc) *App {
-	server.addMethod(http.MethodOptions, server.currentFragment+path, handler)
+// other method handlers or route handlers, optionally configured by route options.
+func (server *App) Options(path string, handler HandlerFunc, options ...RouteOption) *App {
+	server.addMethod(http.MethodOptions, server.fullPath(path), handler, options)
 	return server
 }
 
 // Add a HEAD handler
 //
 // Add a HEAD handler based on where you are in a hierarchy composed from
-// other method handlers or route handlers.
-func (server *App) Head(path string, handler HandlerFunc) *App {
-	server.addMethod(http.MethodHead, server.currentFragment+path, handler)
+// other method handlers or route handlers, optionally configured by route options.
+func (server *App) Head(path string, handler HandlerFunc, options ...RouteOption) *App {
+	server.addMethod(http.MethodHead, server.fullPath(path), handler, options)
 	return server
 }
 
+// Add a handler for any method
+//
+// Add a handler for given method based on where you are in a hierarchy composed from
+// other method handlers or route handlers, optionally configured by route options.
+// Unlike the predefined method helpers, any method can be given, such as the WebDAV
+// methods PROPFIND and REPORT. Methods are matched exactly and case-sensitively.
+func (server *App) Method(method string, path string, handler HandlerFunc, options ...RouteOption) *App {
+	server.addMethod(method, server.fullPath(path), handler, options)
+	return server
+}
+
+// isValidMethod checks whether given method is a valid HTTP method token.
+func isValidMethod(method string) bool {
+	if method == "" {
+		return false
+	}
+
+	for _, char := range method {
+		if !strings.ContainsRune("!#$%&'*+-.^_`|~", char) &&
+			!('0' <= char && char <= '9') &&
+			!('a' <= char && char <= 'z') &&
+			!('A' <= char && char <= 'Z') {
+			return false
+		}
+	}
+
+	return true
+}
+
 // Start the server
 //
-// Start the server based on given configuration.
+// Start the server based on given configuration. Fails without starting if the
+// configuration is invalid or any handler registrations have failed, see
+// RegistrationErrors.
 func (server *App) Start(port ...uint16) error {
 	if server.started {
 		log.Warn("Server is already started")
 		return fmt.Errorf("server has already started")
 	}
+
+	if len(server.config.errors) > 0 {
+		return fmt.Errorf("failed to start server due to invalid config: %s", joinErrors(server.config.errors))
+	}
+
+	if len(server.registrationErrors) > 0 {
+		return fmt.Errorf(
+			"failed to start server due to %d invalid handler registrations: %s",
+			len(server.registrationErrors),
+			joinErrors(server.registrationErrors),
+		)
+	}
+
 	server.started = true
 
 	if len(port) > 0 {
@@ -229,7 +370,9 @@ func (server *App) Start(port ...uint16) error {
 
 // Shutdown the govalin server
 //
-// Start a graceful shutdown of the govalin instance.
+// Start a graceful shutdown of the govalin instance. Responses of requests
+// still in flight are sent with a Connection: close header by net/http,
+// encouraging clients to open fresh connections to healthy instances.
 func (server *App) Shutdown() error {
 	if !server.started {
 		log.Warn("Server was not started")
@@ -244,21 +387,47 @@ func (server *App) Shutdown() error {
 	return server.server.Shutdown(ctx)
 }
 
-func (server *App) getOrCreatePathHandlerByPath(path string) *pathHandler {
+// getOrCreatePathHandlerByPath returns the path handler of given path, creating it if
+// missing. Returns false, recording a registration error, if the path is invalid.
+func (server *App) getOrCreatePathHandlerByPath(path string) (*pathHandler, bool) {
 	if existingPathHandler, pathNotFoundErr := server.getPathHandlerByPath(path); pathNotFoundErr == nil {
-		return existingPathHandler
+		return existingPathHandler, true
 	}
 	newHandler, pathHandlerErr := newPathHandlerFromPathFragment(path)
 	if pathHandlerErr != nil {
-		log.Fatalf("Failed to create before handler for path '%s'. Err %v", path, pathHandlerErr)
+		server.addRegistrationError(pathHandlerErr)
+		return nil, false
 	}
 
 	server.pathHandlers = append(server.pathHandlers, newHandler)
-	handler, err := server.getPathHandlerByPath(path)
-	if err != nil {
-		log.Fatalf("Failed to retrieve newly created handler for path '%s'. Err %v", path, err)
+
+	return &server.pathHandlers[len(server.pathHandlers)-1], true
+}
+
+// addRegistrationError records an error in the registration of handlers, failing Start.
+func (server *App) addRegistrationError(err error) {
+	log.Errorf("Failed to register handler. %v", err)
+	server.registrationErrors = append(server.registrationErrors, err)
+}
+
+// joinErrors joins the messages of given errors into a single message.
+func joinErrors(errs []error) string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
 	}
-	return handler
+
+	return strings.Join(messages, "; ")
+}
+
+// Get the errors of invalid handler registrations
+//
+// RegistrationErrors returns the errors of handler registrations which failed, such as
+// invalid path patterns or handlers registered twice for the same method and path.
+// Start refuses to start the server while there are registration errors, but the
+// errors can be checked up front, e.g. in a test validating the routes of an app.
+func (server *App) RegistrationErrors() []error {
+	return server.registrationErrors
 }
 
 func (server *App) getPathHandlerByPath(path string) (*pathHandler, error) {
@@ -273,37 +442,105 @@ func (server *App) getPathHandlerByPath(path string) (*pathHandler, error) {
 	)
 }
 
+// Lookup the route matching given method and path
+//
+// Lookup runs the route matching without dispatching the request, returning the
+// path pattern of the matched route and the path params extracted from given path.
+// Useful for introspecting which route a URL would hit, e.g. in debugging endpoints.
+func (server *App) Lookup(method string, path string) (string, map[string]string, bool) {
+	pathHandler, found := server.findEndpointHandler(method, path)
+	if !found {
+		return "", map[string]string{}, false
+	}
+
+	return pathHandler.PathFragment, pathHandler.PathMatcher.PathParams(path), true
+}
+
+// findEndpointHandler returns the first path handler with an endpoint handler for
+// given method matching given path.
+func (server *App) findEndpointHandler(method string, path string) (*pathHandler, bool) {
+	for i := range server.pathHandlers {
+		pathHandler := &server.pathHandlers[i]
+		if pathHandler.GetHandlerByMethod(method) != nil && pathHandler.PathMatcher.MatchesURL(path) {
+			return pathHandler, true
+		}
+	}
+
+	return nil, false
+}
+
+// findSPAHandler returns the single-page app handler with the longest prefix matching given request.
+func (server *App) findSPAHandler(req *http.Request) (*spaHandler, bool) {
+	var found *spaHandler
+
+	for i := range server.spaHandlers {
+		spa := &server.spaHandlers[i]
+		if spa.matches(req) && (found == nil || len(spa.prefix) > len(found.prefix)) {
+			found = spa
+		}
+	}
+
+	return found, found != nil
+}
+
 func (server *App) rootHandlerFunc(w http.ResponseWriter, req *http.Request) {
 	w.Header().Add("Server", "govalin")
 
 	handled := false
 
+	writer := newResponseWriter(w)
 	call := newCallFromRequest(
-		w,
+		writer,
 		req,
 		map[string]string{},
+		server.config,
 	)
+	call.errorHandler = server.findErrorHandler(req.URL.Path)
+	defer call.complete(writer)
+
+	if server.requestSlots != nil {
+		select {
+		case server.requestSlots <- struct{}{}:
+			defer func() { <-server.requestSlots }()
+		default:
+			server.serviceUnavailableHandler(&call)
+			return
+		}
+	}
+
+	if server.config.cors != nil && server.config.cors.handle(&call) {
+		return
+	}
+
+	if server.config.variantResolver != nil {
+		call.variants = server.config.variantResolver(&call)
+	}
 
 	// Look for before handlers
 	for _, pathHandler := range server.pathHandlers {
 		if pathHandler.Before != nil && pathHandler.PathMatcher.MatchesURL(req.URL.Path) {
 			call.pathParams = pathHandler.PathMatcher.PathParams(req.URL.Path)
 			handled = true
-			if !pathHandler.Before(&call) {
+			if !pathHandler.Before(&call) || call.aborted {
 				return
 			}
 		}
 	}
 
 	// Look for endpoint handler
-	for _, pathHandler := range server.pathHandlers {
-		if pathHandler.GetHandlerByMethod(req.Method) != nil && pathHandler.PathMatcher.MatchesURL(req.URL.Path) {
-			var handler = pathHandler.GetHandlerByMethod(req.Method)
-			call.pathParams = pathHandler.PathMatcher.PathParams(req.URL.Path)
-			handler(&call)
-			handled = true
-			break
+	if pathHandler, found := server.findEndpointHandler(req.Method, req.URL.Path); found {
+		call.pathParams = pathHandler.PathMatcher.PathParams(req.URL.Path)
+		if len(call.errorDetails) > 0 {
+			call.Error(validation.NewError(validation.NewErrorResponse(http.StatusBadRequest, call.errorDetails...)))
+		} else if err := pathHandler.RouteOptions[req.Method].negotiate(&call); err != nil {
+			call.Error(err)
+		} else {
+			pathHandler.GetHandlerByMethod(req.Method)(&call)
 		}
+		handled = true
+	} else if spa, found := server.findSPAHandler(req); found {
+		spa.handle(&call)
+		handled = true
 	}
 
 	// Look for After handlers
@@ -322,15 +559,11 @@ func (server *App) rootHandlerFunc(w http.ResponseWriter, req *http.Request) {
 	server.notFoundHandler(&call)
 }
 
+func (server *App) serviceUnavailableHandler(call *Call) {
+	call.w.Header().Set("Retry-After", strconv.Itoa(loadSheddingRetryAfterInSeconds))
+	call.Error(newErrorFromType(serviceUnavailableError, fmt.Errorf("the server is at its max concurrent requests")))
+}
+
 func (server *App) notFoundHandler(call *Call) {
-	call.Status(http.StatusNotFound)
-	call.JSON(validation.NewError(
-		validation.NewErrorResponse(
-			http.StatusNotFound,
-			validation.NewParameterErrorDetail(
-				"path",
-				fmt.Sprintf("The path '%s' doesn't exist", call.Raw.Req.URL),
-			),
-		),
-	).ErrorResponse)
+	call.Error(newErrorFromType(notFoundError, fmt.Errorf("The path '%s' doesn't exist", call.Raw.Req.URL)))
 }
@@ -1,8 +1,19 @@
 package govalin_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/ddliu/go-httpclient"
 	"github.com/pkkummermo/govalin"
 	"github.com/pkkummermo/govalin/internal/govalintesting"
 	"github.com/stretchr/testify/assert"
@@ -126,3 +137,589 @@ func TestDelete(t *testing.T) {
 		)
 	})
 }
+
+func TestAbortWithStatus(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Before("/abort", func(call *govalin.Call) bool {
+			call.AbortWithStatus(401)
+			return true
+		})
+		app.Get("/abort", func(call *govalin.Call) {
+			call.Text("govalin")
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response := http.GetResponse("/abort")
+		body, _ := response.ToString()
+
+		assert.Equal(t, 401, response.StatusCode, "Should respond with the abort status")
+		assert.Equal(t, "", body, "Should not run the endpoint handler after abort")
+	})
+}
+
+func TestRouteScope(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Route("/api", func() {
+			app.Route("/users", func() {
+				app.Get("/me", func(call *govalin.Call) {
+					call.Text("me")
+				})
+			})
+			app.Get("/status", func(call *govalin.Call) {
+				call.Text("status")
+			})
+		})
+		app.Get("/docs", func(call *govalin.Call) {
+			call.Text("docs")
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(t, "me", http.Get("/api/users/me"), "Should nest routes")
+		assert.Equal(t, "status", http.Get("/api/status"), "Should restore parent route after nested route")
+		assert.Equal(t, "docs", http.Get("/docs"), "Should restore root after route")
+	})
+}
+
+func TestLookup(t *testing.T) {
+	app := govalin.New()
+	app.Route("/orgs/", func() {
+		app.Get("{org}/repos/{repo}", func(call *govalin.Call) {})
+	})
+
+	pattern, params, found := app.Lookup(http.MethodGet, "/orgs/govalin/repos/core")
+	assert.True(t, found, "Should find matching route")
+	assert.Equal(t, "/orgs/{org}/repos/{repo}", pattern, "Should return the route pattern")
+	assert.Equal(t, map[string]string{"org": "govalin", "repo": "core"}, params, "Should extract path params")
+
+	_, _, found = app.Lookup(http.MethodPost, "/orgs/govalin/repos/core")
+	assert.False(t, found, "Should not match route registered for another method")
+}
+
+func TestMiddleware(t *testing.T) {
+	app := govalin.New()
+	app.After("/", func(call *govalin.Call) {}, "logging")
+	app.Before("/api/users", func(call *govalin.Call) bool { return true }, "auth")
+	app.Before("/", func(call *govalin.Call) bool { return true })
+
+	assert.Equal(t, []govalin.MiddlewareInfo{
+		{Stage: govalin.MiddlewareStageBefore, Path: "/", Name: ""},
+		{Stage: govalin.MiddlewareStageBefore, Path: "/api/users", Name: "auth"},
+		{Stage: govalin.MiddlewareStageAfter, Path: "/", Name: "logging"},
+	}, app.Middleware(), "Should list middleware in execution order")
+}
+
+func TestProducesConsumes(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Get("/produces", func(call *govalin.Call) {
+			call.JSON("govalin")
+		}, govalin.Produces("application/json"))
+		app.Post("/consumes", func(call *govalin.Call) {
+			call.Text("govalin")
+		}, govalin.Consumes("application/json"))
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response, _ := http.Raw().WithHeader("Accept", "application/*").Get(http.Host + "/produces")
+		assert.Equal(t, 200, response.StatusCode, "Should accept compatible Accept header")
+
+		response, _ = http.Raw().WithHeader("Accept", "text/html").Get(http.Host + "/produces")
+		assert.Equal(t, 406, response.StatusCode, "Should reject incompatible Accept header")
+
+		response, _ = http.Raw().WithHeader("Accept", "application/json;q=0, */*").Get(http.Host + "/produces")
+		assert.Equal(t, 406, response.StatusCode, "Should let an explicit q=0 override a wildcard")
+
+		response, _ = http.Raw().PostJson(http.Host+"/consumes", map[string]string{})
+		assert.Equal(t, 200, response.StatusCode, "Should accept consumed content type")
+
+		response, _ = http.Raw().Post(http.Host+"/consumes", map[string]string{"name": "govalin"})
+		assert.Equal(t, 415, response.StatusCode, "Should reject unsupported content type")
+	})
+}
+
+func TestCORS(t *testing.T) {
+	govalintesting.HTTPTestUtilWithConfig(func(config *govalin.Config) {
+		config.CORS(func(cors *govalin.CORSConfig) {
+			cors.AllowOrigins("https://govalin.dev").MaxAge(time.Hour)
+		})
+	}, func(app *govalin.App) *govalin.App {
+		app.Get("/cors", func(call *govalin.Call) {
+			call.Text("govalin")
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response, _ := http.Raw().WithHeaders(map[string]string{
+			"Origin":                         "https://govalin.dev",
+			"Access-Control-Request-Method":  "GET",
+			"Access-Control-Request-Headers": "X-Govalin",
+		}).Options(http.Host + "/cors")
+
+		assert.Equal(t, 204, response.StatusCode, "Should answer preflight request")
+		assert.Equal(t, "3600", response.Header.Get("Access-Control-Max-Age"), "Should set configured max age")
+		assert.Equal(t, "X-Govalin", response.Header.Get("Access-Control-Allow-Headers"), "Should allow requested headers")
+		assert.Equal(
+			t,
+			[]string{"Origin", "Access-Control-Request-Method, Access-Control-Request-Headers"},
+			response.Header.Values("Vary"),
+			"Should vary preflight response on origin and requested method and headers",
+		)
+
+		response, _ = http.Raw().WithHeader("Origin", "https://govalin.dev").Get(http.Host + "/cors")
+		body, _ := response.ToString()
+		assert.Equal(t, "govalin", body, "Should handle cross-origin request")
+		assert.Equal(t, "https://govalin.dev", response.Header.Get("Access-Control-Allow-Origin"))
+
+		response, _ = http.Raw().WithHeader("Origin", "https://evil.dev").Get(http.Host + "/cors")
+		assert.Empty(t, response.Header.Get("Access-Control-Allow-Origin"), "Should not allow unknown origins")
+	})
+}
+
+func TestAccumulatedValidationErrors(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Before("/validate", func(call *govalin.Call) bool {
+			if call.Header("Authorization") == "" {
+				call.AddValidationError("Authorization", "Missing authorization header")
+			}
+			return true
+		})
+		app.Before("/*", func(call *govalin.Call) bool {
+			if call.QueryParam("name") == "" {
+				call.AddValidationError("name", "Missing name query param")
+			}
+			return true
+		})
+		app.Get("/validate", func(call *govalin.Call) {
+			call.Text("govalin")
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response := http.GetResponse("/validate")
+		body, _ := response.ToString()
+
+		assert.Equal(t, 400, response.StatusCode, "Should respond bad request on validation errors")
+		assert.Contains(t, body, "Missing authorization header", "Should contain first validation error")
+		assert.Contains(t, body, "Missing name query param", "Should contain second validation error")
+
+		response, _ = http.Raw().WithHeader("Authorization", "govalin").Get(http.Host + "/validate?name=govalin")
+		body, _ = response.ToString()
+		assert.Equal(t, "govalin", body, "Should run handler without validation errors")
+	})
+}
+
+func TestResponseFilter(t *testing.T) {
+	govalintesting.HTTPTestUtilWithConfig(func(config *govalin.Config) {
+		config.ResponseFilter(func(call *govalin.Call, contentType string, body []byte) []byte {
+			if !strings.HasPrefix(contentType, "application/json") {
+				return body
+			}
+			return append(append([]byte(`{"data":`), body...), '}')
+		}).ResponseFilter(func(call *govalin.Call, contentType string, body []byte) []byte {
+			return bytes.ToUpper(body)
+		})
+	}, func(app *govalin.App) *govalin.App {
+		app.Get("/json", func(call *govalin.Call) {
+			call.JSON("govalin")
+		})
+		app.Get("/text", func(call *govalin.Call) {
+			call.Text("govalin")
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(t, `{"DATA":"GOVALIN"}`, http.Get("/json"), "Should run filters in order")
+		assert.Equal(t, "GOVALIN", http.Get("/text"), "Should pass content type to filters")
+	})
+}
+
+func TestEnableProfiling(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.EnableProfiling("/debug/pprof", func(call *govalin.Call) bool {
+			return call.Header("Authorization") == "govalin"
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(t, 403, http.GetResponse("/debug/pprof").StatusCode, "Should guard profiling endpoints")
+		assert.Equal(t, 403, http.GetResponse("/debug/pprof/heap").StatusCode, "Should guard named profiles")
+
+		response, _ := http.Raw().WithHeader("Authorization", "govalin").Get(http.Host + "/debug/pprof")
+		body, _ := response.ToString()
+		assert.Contains(t, body, `href="/debug/pprof/goroutine?debug=1"`, "Should list profiles in index")
+
+		response, _ = http.Raw().WithHeader("Authorization", "govalin").Get(http.Host + "/debug/pprof/goroutine?debug=1")
+		body, _ = response.ToString()
+		assert.Contains(t, body, "goroutine profile", "Should serve named profiles")
+
+		response, _ = http.Raw().WithHeader("Authorization", "govalin").Get(http.Host + "/debug/pprof/vars")
+		body, _ = response.ToString()
+		assert.Contains(t, body, "memstats", "Should serve expvar")
+	})
+}
+
+// unreadableFS is a filesystem where files can be stat'ed, but not opened.
+type unreadableFS struct {
+	fstest.MapFS
+}
+
+func (unreadableFS) Open(name string) (fs.File, error) {
+	return nil, fs.ErrPermission
+}
+
+func TestStaticFS(t *testing.T) {
+	var compressed bytes.Buffer
+	gzipWriter := gzip.NewWriter(&compressed)
+	_, _ = gzipWriter.Write([]byte("console.log('govalin')"))
+	_ = gzipWriter.Close()
+
+	fsys := fstest.MapFS{
+		"index.html":   &fstest.MapFile{Data: []byte("<h1>govalin</h1>")},
+		"js/app.js":    &fstest.MapFile{Data: []byte("console.log('govalin')")},
+		"js/app.js.gz": &fstest.MapFile{Data: compressed.Bytes()},
+	}
+
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.StaticFS("/assets", fsys)
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response := http.GetResponse("/assets/index.html")
+		body, _ := response.ToString()
+		assert.Equal(t, "<h1>govalin</h1>", body, "Should serve static file")
+
+		response, _ = http.Raw().WithHeader("Accept-Encoding", "identity").Get(http.Host + "/assets/js/app.js")
+		body, _ = response.ToString()
+		assert.Equal(t, "console.log('govalin')", body, "Should serve uncompressed file when gzip isn't accepted")
+		assert.Empty(t, response.Header.Get("Content-Encoding"))
+
+		response, _ = http.Raw().WithHeader("Accept-Encoding", "gzip").Get(http.Host + "/assets/js/app.js")
+		body, _ = response.ToString()
+		// The client transparently decompresses the body
+		assert.Equal(t, "console.log('govalin')", body, "Should serve pre-compressed file when gzip is accepted")
+		assert.Equal(t, "gzip", response.Header.Get("Content-Encoding"), "Should set content encoding")
+		assert.Equal(t, "text/javascript; charset=utf-8", response.Header.Get("Content-Type"))
+
+		response, _ = http.Raw().WithHeader("Accept-Encoding", "gzip;q=0, *").Get(http.Host + "/assets/js/app.js")
+		assert.Empty(t, response.Header.Get("Content-Encoding"), "Should let an explicit q=0 override a wildcard")
+
+		assert.Equal(t, 404, http.GetResponse("/assets/missing.js").StatusCode, "Should respond 404 on missing file")
+
+		response = http.HeadResponse("/assets/js/app.js")
+		assert.Equal(t, 200, response.StatusCode, "Should serve HEAD requests")
+		assert.Equal(t, "text/javascript; charset=utf-8", response.Header.Get("Content-Type"))
+	})
+
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.StaticFS("/assets", unreadableFS{fsys})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(t, 500, http.GetResponse("/assets/index.html").StatusCode, "Should respond 500 on unreadable file")
+
+		response, _ := http.Raw().WithHeader("Accept-Encoding", "gzip").Get(http.Host + "/assets/js/app.js")
+		assert.Equal(t, 500, response.StatusCode, "Should respond 500 on unreadable pre-compressed file")
+		assert.Empty(t, response.Header.Get("Content-Encoding"), "Should not label the error response as compressed")
+	})
+}
+
+func TestFromOpenAPI(t *testing.T) {
+	spec := []byte(`
+openapi: 3.0.3
+info:
+  title: Govalin
+  version: 1.0.0
+paths:
+  /users/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+    get:
+      operationId: getUser
+    delete:
+      operationId: deleteUser
+`)
+
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		err := app.FromOpenAPI(spec, map[string]govalin.HandlerFunc{
+			"getUser": func(call *govalin.Call) {
+				call.Text("user " + call.PathParam("id"))
+			},
+			"deleteUser": func(call *govalin.Call) {
+				call.Status(204)
+				call.Text("")
+			},
+		})
+		assert.NoError(t, err)
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(t, "user govalin", http.Get("/users/govalin"), "Should bind operation to handler")
+		assert.Equal(t, 204, http.DeleteResponse("/users/govalin").StatusCode, "Should register every operation")
+	})
+
+	err := govalin.New().FromOpenAPI(spec, map[string]govalin.HandlerFunc{
+		"getUser": func(call *govalin.Call) {},
+	})
+	assert.ErrorContains(t, err, "missing handler for operation 'deleteUser'", "Should fail on missing handlers")
+}
+
+func TestSPA(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<app/>"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "app.js"), []byte("govalin()"), 0o600))
+
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.SPA("/app", dir, "index.html")
+		app.Get("/app/api/users", func(call *govalin.Call) {
+			call.Text("users")
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(t, "govalin()", http.Get("/app/app.js"), "Should serve existing files")
+		assert.Equal(t, "users", http.Get("/app/api/users"), "Should give API routes precedence")
+
+		response := http.GetResponse("/app/users/42")
+		body, _ := response.ToString()
+		assert.Equal(t, 200, response.StatusCode, "Should serve index for client-side routes")
+		assert.Equal(t, "<app/>", body, "Should serve index for client-side routes")
+
+		assert.Equal(t, 404, http.GetResponse("/app/missing.js").StatusCode, "Should 404 on missing assets")
+		assert.Equal(t, 404, http.GetResponse("/other").StatusCode, "Should not handle paths outside prefix")
+	})
+}
+
+func TestCustomMethod(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Method("PROPFIND", "/files", func(call *govalin.Call) {
+			call.Status(207)
+			call.Text("propfind")
+		})
+		app.Method(http.MethodGet, "/files", func(call *govalin.Call) {
+			call.Text("get")
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response, _ := http.Raw().Do("PROPFIND", http.Host+"/files", nil, nil)
+		body, _ := response.ToString()
+		assert.Equal(t, 207, response.StatusCode, "Should route custom methods")
+		assert.Equal(t, "propfind", body, "Should route custom methods")
+
+		response, _ = http.Raw().Do("propfind", http.Host+"/files", nil, nil)
+		assert.Equal(t, 404, response.StatusCode, "Should match methods case-sensitively")
+
+		assert.Equal(t, "get", http.Get("/files"), "Should register predefined methods")
+	})
+}
+
+func TestMaxConcurrentRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	govalintesting.HTTPTestUtilWithConfig(func(config *govalin.Config) {
+		config.MaxConcurrentRequests(1)
+	}, func(app *govalin.App) *govalin.App {
+		app.ErrorHandler(func(call *govalin.Call, err error) {
+			call.Status(call.StatusForError(err))
+			call.HTML("<h1>Busy</h1>")
+		})
+		app.Get("/slow", func(call *govalin.Call) {
+			started <- struct{}{}
+			<-release
+			call.Text("slow")
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		slowResult := make(chan string)
+		go func() {
+			slowResult <- http.Get("/slow")
+		}()
+		<-started
+
+		response := http.GetResponse("/slow")
+		assert.Equal(t, 503, response.StatusCode, "Should reject requests over the limit")
+		assert.Equal(t, "1", response.Header.Get("Retry-After"), "Should ask client to retry later")
+		body, _ := response.ToString()
+		assert.Equal(t, "<h1>Busy</h1>", body, "Should reject requests using the error handler")
+
+		release <- struct{}{}
+		assert.Equal(t, "slow", <-slowResult, "Should handle requests within the limit")
+
+		go func() { <-started; release <- struct{}{} }()
+		assert.Equal(t, "slow", http.Get("/slow"), "Should free slots when requests finish")
+	})
+}
+
+func TestShutdownClosesConnections(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	var server *govalin.App
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		server = app
+		app.Get("/slow", func(call *govalin.Call) {
+			started <- struct{}{}
+			<-release
+			call.Text("slow")
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		slowResponse := make(chan *httpclient.Response)
+		go func() {
+			slowResponse <- http.GetResponse("/slow")
+		}()
+		<-started
+
+		shutdownResult := make(chan error)
+		go func() {
+			shutdownResult <- server.Shutdown()
+		}()
+		// Give the shutdown time to start before finishing the request in flight
+		time.Sleep(10 * time.Millisecond)
+		release <- struct{}{}
+
+		response := <-slowResponse
+		body, _ := response.ToString()
+		assert.Equal(t, "slow", body, "Should finish requests in flight")
+		// net/http sends Connection: close while shutting down, which the client consumes by marking
+		// the response as closing the connection
+		assert.True(t, response.Close, "Should close connections when shutting down")
+		assert.NoError(t, <-shutdownResult)
+	})
+}
+
+func TestErrorHandler(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.ErrorHandler(func(call *govalin.Call, err error) {
+			call.Status(call.StatusForError(err))
+			call.HTML("<h1>Oops</h1>")
+		})
+		app.Route("/api", func() {
+			app.ErrorHandler(func(call *govalin.Call, err error) {
+				call.Status(call.StatusForError(err))
+				call.JSON(map[string]string{"error": err.Error()})
+			})
+			app.Get("/users", func(call *govalin.Call) {
+				call.Error(errors.New("no users"))
+			})
+		})
+		app.Get("/apidocs", func(call *govalin.Call) {
+			call.Error(errors.New("no docs"))
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response := http.GetResponse("/api/users")
+		body, _ := response.ToString()
+		assert.Equal(t, 500, response.StatusCode)
+		assert.Equal(t, `{"error":"no users"}`, body, "Should use the most specific error handler")
+
+		response = http.GetResponse("/apidocs")
+		body, _ = response.ToString()
+		assert.Equal(t, 500, response.StatusCode)
+		assert.Equal(t, "<h1>Oops</h1>", body, "Should fall back to the app error handler")
+
+		response = http.GetResponse("/missing")
+		body, _ = response.ToString()
+		assert.Equal(t, 404, response.StatusCode)
+		assert.Equal(t, "<h1>Oops</h1>", body, "Should handle missing paths using the error handler")
+
+		response = http.GetResponse("/api/missing")
+		body, _ = response.ToString()
+		assert.Equal(t, 404, response.StatusCode)
+		assert.Contains(t, body, `"error"`, "Should handle missing paths using the scoped error handler")
+	})
+}
+
+func TestErrorHandlerWithPathParams(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Route("/users/{id}", func() {
+			app.ErrorHandler(func(call *govalin.Call, err error) {
+				call.Status(call.StatusForError(err))
+				call.Text("user " + call.PathParam("id") + ": " + err.Error())
+			})
+			app.Get("/posts", func(call *govalin.Call) {
+				call.Error(errors.New("no posts"))
+			})
+		})
+		app.Get("/users", func(call *govalin.Call) {
+			call.Error(errors.New("no users"))
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		response := http.GetResponse("/users/5/posts")
+		body, _ := response.ToString()
+		assert.Equal(t, 500, response.StatusCode)
+		assert.Equal(t, "user 5: no posts", body, "Should use the error handler of the parameterised route")
+
+		response = http.GetResponse("/users")
+		body, _ = response.ToString()
+		assert.Equal(t, 500, response.StatusCode)
+		assert.NotContains(t, body, "no users", "Should use default handling outside of the route")
+	})
+}
+
+func TestRegistrationErrors(t *testing.T) {
+	app := govalin.New().
+		Get("/users/{id", func(call *govalin.Call) {}).
+		Get("/users//posts", func(call *govalin.Call) {}).
+		Get("/users", func(call *govalin.Call) {}).
+		Get("/users", func(call *govalin.Call) {}).
+		Method("PROP FIND", "/users", func(call *govalin.Call) {}).
+		Get("/users/pre{id}", func(call *govalin.Call) {}).
+		Get("/users/{x}{y}", func(call *govalin.Call) {}).
+		Get("/users/}id{", func(call *govalin.Call) {}).
+		Get("/users/{}", func(call *govalin.Call) {})
+
+	errs := app.RegistrationErrors()
+	assert.Len(t, errs, 8, "Should collect all registration errors")
+	assert.Contains(t, errs[0].Error(), "unbalanced braces in path segment '{id'", "Should reject unbalanced braces")
+	assert.Contains(t, errs[1].Error(), "contains an empty segment", "Should reject empty segments")
+	assert.Contains(t, errs[2].Error(), "GET already exists on path /users", "Should reject duplicate routes")
+	assert.Contains(t, errs[3].Error(), "invalid method 'PROP FIND'", "Should reject invalid methods")
+	assert.Contains(t, errs[4].Error(), "unsupported path segment 'pre{id}'", "Should reject partial params")
+	assert.Contains(t, errs[5].Error(), "unsupported path segment '{x}{y}'", "Should reject multiple params")
+	assert.Contains(t, errs[6].Error(), "unsupported path segment '}id{'", "Should reject reversed braces")
+	assert.Contains(t, errs[7].Error(), "missing param name in path segment '{}'", "Should reject unnamed params")
+
+	err := app.Start()
+	assert.Error(t, err, "Should refuse to start with registration errors")
+	assert.Contains(t, err.Error(), "8 invalid handler registrations")
+}
+
+func TestInvalidConfig(t *testing.T) {
+	app := govalin.New(func(config *govalin.Config) {
+		config.TrustedProxies("10.0.0.0/33", "127.0.0.1")
+	})
+
+	err := app.Start()
+	assert.Error(t, err, "Should refuse to start with an invalid config")
+	assert.Contains(t, err.Error(), "invalid trusted proxy '10.0.0.0/33'", "Should describe the invalid config")
+}
+
+func TestRouteTrailingSlash(t *testing.T) {
+	govalintesting.HTTPTestUtil(func(app *govalin.App) *govalin.App {
+		app.Route("/", func() {
+			app.Get("/x", func(call *govalin.Call) {
+				call.Text("x")
+			})
+		})
+		app.Route("/api/", func() {
+			app.Get("/users", func(call *govalin.Call) {
+				call.Text("users")
+			})
+		})
+
+		return app
+	}, func(http govalintesting.GovalinHTTP) {
+		assert.Equal(t, "x", http.Get("/x"), "Should join root route and path")
+		assert.Equal(t, "users", http.Get("/api/users"), "Should join route with trailing slash and path")
+	})
+}
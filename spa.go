@@ -0,0 +1,64 @@
+package govalin
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// spaHandler serves a single-page app under a prefix.
+type spaHandler struct {
+	prefix    string
+	fsys      fs.FS
+	indexFile string
+}
+
+// Serve a single-page app
+//
+// SPA serves the files in given directory under given prefix like Static, but falls back to
+// serving given index file with a 200 OK for paths not matching a file, so that client-side
+// routing works when reloading or deep linking. Paths looking like asset files, i.e. having
+// a file extension, are still answered with a 404 when missing. The SPA only handles GET and
+// HEAD requests not matched by any other endpoint handler, so API routes always take
+// precedence regardless of the order they are registered in.
+func (server *App) SPA(prefix string, dir string, indexFile string) *App {
+	server.spaHandlers = append(server.spaHandlers, spaHandler{
+		prefix:    strings.TrimSuffix(server.fullPath(prefix), "/"),
+		fsys:      os.DirFS(dir),
+		indexFile: indexFile,
+	})
+
+	return server
+}
+
+// matches checks whether given request should be handled by the SPA.
+func (spa *spaHandler) matches(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	return req.URL.Path == spa.prefix || strings.HasPrefix(req.URL.Path, spa.prefix+"/")
+}
+
+func (spa *spaHandler) handle(call *Call) {
+	name := strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(call.req.URL.Path, spa.prefix)), "/")
+
+	if info, err := fs.Stat(spa.fsys, name); name != "" && err == nil && !info.IsDir() {
+		if serveErr := call.serveStaticFile(spa.fsys, name); serveErr != nil {
+			call.Error(serveErr)
+		}
+		return
+	}
+
+	if path.Ext(name) != "" {
+		call.Error(newErrorFromType(notFoundError, fmt.Errorf("the file '%s' doesn't exist", name)))
+		return
+	}
+
+	if err := call.serveStaticFile(spa.fsys, spa.indexFile); err != nil {
+		call.Error(err)
+	}
+}
@@ -0,0 +1,131 @@
+package govalin
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSEStream writes server-sent events to the response.
+//
+// The methods of a stream are safe to call from multiple goroutines, allowing a
+// broadcaster to fan out events to the streams of its subscribers while the handlers
+// are waiting. Each event is written and flushed in full before the next is written.
+// The handler owning the stream must keep running while other goroutines send events,
+// and should close the stream before returning, making later sends fail instead of
+// writing to a finished response:
+//
+//	stream := call.SSE()
+//	defer stream.Close()
+//	broker.Subscribe(stream)
+//	<-call.Done()
+type SSEStream struct {
+	call   *Call
+	mutex  sync.Mutex
+	closed bool
+}
+
+// Stream server-sent events to the response
+//
+// SSE sets the headers for a text/event-stream response, sends the status and returns
+// a stream for sending events. The handler should keep running for as long as events
+// are to be sent, as the response is finished when the handler returns.
+func (call *Call) SSE() *SSEStream {
+	call.w.Header().Set("Content-Type", "text/event-stream")
+	call.w.Header().Set("Cache-Control", "no-cache")
+	call.w.Header().Set("X-Accel-Buffering", "no")
+	call.w.Header().Set("Accept-Ranges", "none")
+	call.sendStatusOrDefault()
+
+	stream := &SSEStream{call: call}
+	stream.flush()
+
+	return stream
+}
+
+// Get the ID of the last event received by the client
+//
+// LastEventID returns the Last-Event-ID header sent by clients reconnecting to a
+// server-sent event stream, allowing the stream to resume from where the client left
+// off. Returns an empty string on the initial connection.
+func (call *Call) LastEventID() string {
+	return call.req.Header.Get("Last-Event-ID")
+}
+
+// lineBreakNormalizer replaces the line breaks recognized by the event stream format with "\n".
+var lineBreakNormalizer = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// SendEvent sends an event with given name and data. An empty event name sends an
+// unnamed message event. Data spanning multiple lines is sent as multiple data fields.
+func (stream *SSEStream) SendEvent(event string, data string) error {
+	return stream.SendEventWithID("", event, data)
+}
+
+// SendEventWithID sends an event with given ID, name and data. The ID is tracked by the
+// client and sent as the Last-Event-ID header when reconnecting. Returns an error without
+// sending anything if the ID or name contains a line break, as it would end the field.
+func (stream *SSEStream) SendEventWithID(id string, event string, data string) error {
+	if strings.ContainsAny(id, "\r\n") {
+		return fmt.Errorf("failed to send server-sent event. The ID %q contains a line break", id)
+	}
+
+	if strings.ContainsAny(event, "\r\n") {
+		return fmt.Errorf("failed to send server-sent event. The event name %q contains a line break", event)
+	}
+
+	var message strings.Builder
+
+	if id != "" {
+		message.WriteString("id: " + id + "\n")
+	}
+
+	if event != "" {
+		message.WriteString("event: " + event + "\n")
+	}
+
+	for _, line := range strings.Split(lineBreakNormalizer.Replace(data), "\n") {
+		message.WriteString("data: " + line + "\n")
+	}
+
+	message.WriteString("\n")
+
+	return stream.write(message.String())
+}
+
+// SetRetry tells the client how long to wait before reconnecting if the connection is lost.
+func (stream *SSEStream) SetRetry(retry time.Duration) error {
+	return stream.write(fmt.Sprintf("retry: %d\n\n", retry.Milliseconds()))
+}
+
+// Close the stream, making subsequent sends fail. Waits for any send in progress to finish.
+func (stream *SSEStream) Close() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	stream.closed = true
+}
+
+func (stream *SSEStream) write(message string) error {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	if stream.closed {
+		return fmt.Errorf("failed to write server-sent event. The stream is closed")
+	}
+
+	if _, err := stream.call.w.Write([]byte(message)); err != nil {
+		return fmt.Errorf("failed to write server-sent event. %w", err)
+	}
+
+	stream.flush()
+
+	return nil
+}
+
+func (stream *SSEStream) flush() {
+	if flusher, ok := stream.call.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,134 @@
+package govalin
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkkummermo/govalin/internal/negotiation"
+)
+
+// Serve static files from a directory
+//
+// Static serves the files in given directory under given prefix, see StaticFS.
+func (server *App) Static(prefix string, dir string) *App {
+	return server.StaticFS(prefix, os.DirFS(dir))
+}
+
+// Serve static files from a filesystem
+//
+// StaticFS serves the files in given filesystem, e.g. an embed.FS, under given prefix.
+// Requests for directories serve the index.html of the directory. If the client accepts
+// gzip and a pre-compressed <file>.gz exists alongside the requested file, the compressed
+// file is served with the content type of the original file, saving the cost of
+// compressing on the fly. Missing files are answered with a 404. Both GET and HEAD
+// requests are served.
+func (server *App) StaticFS(prefix string, fsys fs.FS) *App {
+	fullPrefix := strings.TrimSuffix(server.fullPath(prefix), "/")
+
+	handler := func(call *Call) {
+		name := strings.TrimPrefix(call.req.URL.Path, fullPrefix+"/")
+		if err := call.serveStaticFile(fsys, name); err != nil {
+			call.Error(err)
+		}
+	}
+	server.Get(strings.TrimSuffix(prefix, "/")+"/*", handler)
+	server.Head(strings.TrimSuffix(prefix, "/")+"/*", handler)
+
+	return server
+}
+
+// serveStaticFile serves the file with given name from given filesystem.
+func (call *Call) serveStaticFile(fsys fs.FS, name string) error {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		name = "."
+	}
+
+	info, err := fs.Stat(fsys, name)
+	if err == nil && info.IsDir() {
+		name = path.Join(name, "index.html")
+		info, err = fs.Stat(fsys, name)
+	}
+
+	if err != nil || info.IsDir() {
+		return newErrorFromType(notFoundError, fmt.Errorf("the file '%s' doesn't exist", name))
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	contentEncoding := ""
+
+	if gzipInfo, gzipErr := fs.Stat(fsys, name+".gz"); gzipErr == nil && !gzipInfo.IsDir() {
+		call.w.Header().Add("Vary", "Accept-Encoding")
+
+		if negotiation.AcceptsEncoding(call.req.Header.Get("Accept-Encoding"), "gzip") {
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+			contentEncoding = "gzip"
+			name, info = name+".gz", gzipInfo
+		}
+	}
+
+	content, err := readSeeker(fsys, name)
+	if err != nil {
+		return newErrorFromType(serverError, fmt.Errorf("failed to read file '%s'. %w", name, err))
+	}
+	defer content.Close()
+
+	// The headers describe the file, so they are only set once it's known the file can be served
+	if contentEncoding != "" {
+		call.w.Header().Set("Content-Encoding", contentEncoding)
+	}
+
+	if contentType != "" {
+		call.setContentTypeIfAbsent(contentType)
+	}
+
+	call.w.Header().Set("Accept-Ranges", "bytes")
+	http.ServeContent(call.w, call.req, name, info.ModTime(), content)
+	call.statusWritten = true
+
+	return nil
+}
+
+// readSeekCloser is a file which can be both read and seeked.
+type readSeekCloser interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+// readSeeker opens given file in given filesystem for serving. Files which can't be
+// seeked are read into memory.
+func readSeeker(fsys fs.FS, name string) (readSeekCloser, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if seekableFile, ok := file.(readSeekCloser); ok {
+		return seekableFile, nil
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return nopCloser{bytes.NewReader(data)}, nil
+}
+
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,184 @@
+package govalin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkkummermo/govalin/internal/validation"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// rewriteTimeFields rewrites values of time.Time fields having a `time` struct tag
+// from the layout given by the tag into RFC 3339, which encoding/json understands.
+// The body is returned untouched if the target type has no such fields or isn't valid
+// JSON, leaving the error reporting to the actual unmarshalling.
+func rewriteTimeFields(bodyBytes []byte, targetType reflect.Type) ([]byte, error) {
+	if !hasTimeLayoutFields(targetType, map[reflect.Type]bool{}) {
+		return bodyBytes, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(bodyBytes))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return bodyBytes, nil
+	}
+
+	if err := rewriteTimeValue(value, targetType, "jsonBody"); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(value)
+}
+
+// hasTimeLayoutFields checks whether given type contains any fields with a `time` struct tag.
+func hasTimeLayoutFields(valueType reflect.Type, visited map[reflect.Type]bool) bool {
+	valueType = derefType(valueType)
+	if visited[valueType] {
+		return false
+	}
+	visited[valueType] = true
+
+	switch valueType.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return hasTimeLayoutFields(valueType.Elem(), visited)
+	case reflect.Struct:
+		for i := 0; i < valueType.NumField(); i++ {
+			field := valueType.Field(i)
+			if field.Tag.Get("time") != "" || hasTimeLayoutFields(field.Type, visited) {
+				return true
+			}
+		}
+	default:
+	}
+
+	return false
+}
+
+func rewriteTimeValue(value any, valueType reflect.Type, path string) error {
+	valueType = derefType(valueType)
+
+	switch valueType.Kind() {
+	case reflect.Struct:
+		if object, ok := value.(map[string]any); ok {
+			return rewriteTimeStruct(object, valueType, path)
+		}
+	case reflect.Slice, reflect.Array:
+		if array, ok := value.([]any); ok {
+			for i, element := range array {
+				if err := rewriteTimeValue(element, valueType.Elem(), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Map:
+		if object, ok := value.(map[string]any); ok {
+			for key, element := range object {
+				if err := rewriteTimeValue(element, valueType.Elem(), path+"."+key); err != nil {
+					return err
+				}
+			}
+		}
+	default:
+	}
+
+	return nil
+}
+
+// rewriteTimeStruct rewrites the time fields of given JSON object decoded into given
+// struct type. Fields of embedded structs are promoted like encoding/json does.
+func rewriteTimeStruct(object map[string]any, structType reflect.Type, path string) error {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+
+		name, tagged := jsonFieldName(field)
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && !tagged && derefType(field.Type).Kind() == reflect.Struct {
+			if err := rewriteTimeStruct(object, derefType(field.Type), path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		key, found := jsonObjectKey(object, name)
+		if !found {
+			continue
+		}
+
+		layout := field.Tag.Get("time")
+		if layout == "" || derefType(field.Type) != timeType {
+			if err := rewriteTimeValue(object[key], field.Type, path+"."+name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		timeString, ok := object[key].(string)
+		if !ok {
+			continue
+		}
+
+		parsedTime, err := time.Parse(layout, timeString)
+		if err != nil {
+			return validation.NewError(validation.NewErrorResponse(
+				http.StatusBadRequest,
+				validation.NewParameterErrorDetail(
+					path+"."+name,
+					fmt.Sprintf("Incorrect format. '%s' is not a time of format '%s'", timeString, layout),
+				),
+			))
+		}
+
+		object[key] = parsedTime.Format(time.RFC3339Nano)
+	}
+
+	return nil
+}
+
+// jsonFieldName returns the JSON name of given struct field, and whether the name
+// was given by a json struct tag.
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name, false
+	}
+
+	return name, true
+}
+
+// jsonObjectKey finds the key of given JSON object matching given field name,
+// preferring an exact match over a case-insensitive one like encoding/json.
+func jsonObjectKey(object map[string]any, name string) (string, bool) {
+	if _, found := object[name]; found {
+		return name, true
+	}
+
+	for key := range object {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+
+	return "", false
+}
+
+func derefType(valueType reflect.Type) reflect.Type {
+	for valueType.Kind() == reflect.Pointer {
+		valueType = valueType.Elem()
+	}
+
+	return valueType
+}
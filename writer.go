@@ -0,0 +1,72 @@
+package govalin
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// responseWriter wraps a http.ResponseWriter, keeping track of the written
+// status and the number of bytes written to the response.
+type responseWriter struct {
+	http.ResponseWriter
+	status       int
+	bytesWritten int64
+}
+
+func newResponseWriter(w http.ResponseWriter) *responseWriter {
+	return &responseWriter{ResponseWriter: w}
+}
+
+func (rw *responseWriter) WriteHeader(statusCode int) {
+	if rw.status == 0 {
+		rw.status = statusCode
+	}
+
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rw *responseWriter) Write(bytes []byte) (int, error) {
+	if rw.status == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	written, err := rw.ResponseWriter.Write(bytes)
+	rw.bytesWritten += int64(written)
+
+	return written, err
+}
+
+// Flush flushes the underlying writer if it supports flushing.
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Status returns the status written to the response. If nothing has been
+// written yet, the status net/http will send by default is returned.
+func (rw *responseWriter) Status() int {
+	if rw.status == 0 {
+		return http.StatusOK
+	}
+
+	return rw.status
+}
+
+// Hijack lets the handler take over the connection, e.g. for upgrading it to a websocket,
+// if the underlying writer supports it.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("the underlying response writer doesn't support hijacking")
+	}
+
+	return hijacker.Hijack()
+}
+
+// Unwrap returns the underlying writer, letting http.ResponseController reach its features.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}